@@ -1,77 +1,165 @@
 package main
 
 import (
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	path "path/filepath"
+	"regexp"
 	"strings"
+	"time"
+	"vencordinstaller/buildinfo"
 )
 
-var PackageJson = `{
-	"name": "discord",
-	"main": "index.js"
-}`
+type PatchState int
 
-type asarEntry struct {
-	Size   int32  `json:"size"`
-	Offset string `json:"offset"`
+const (
+	PatchStateUnpatched    PatchState = iota
+	PatchStateVencord                 // app.asar is our shim and loads the current patcher
+	PatchStateStalePatcher            // app.asar is our shim, but the patcher it loads is missing or not the current one
+	PatchStateForeign                 // _app.asar exists, but app.asar was not written by us
+)
+
+// ShimMetadata is embedded into the package.json of the app.asar we write
+type ShimMetadata struct {
+	InstallerVersion string    `json:"installerVersion"`
+	InstallerGitHash string    `json:"installerGitHash"`
+	PatchedAt        time.Time `json:"patchedAt"`
+	Patcher          string    `json:"patcher"`
 }
 
-// Ported from https://github.com/GeopJr/asar-cr/blob/cd7695b7c913bf921d9fb6600eaeb1400e3ba225/src/asar-cr/pack.cr#L61
+// IsLegacy reports whether the shim was written by an installer that did not embed metadata yet
+func (m *ShimMetadata) IsLegacy() bool {
+	return m.PatchedAt.IsZero()
+}
 
-func WriteAppAsar(outFile string, patcherPath string) error {
-	header := make(map[string]map[string]asarEntry)
-	files := make(map[string]asarEntry)
-	header["files"] = files
+type shimPackageJson struct {
+	Name             string        `json:"name"`
+	Main             string        `json:"main"`
+	VencordInstaller *ShimMetadata `json:"vencordInstaller,omitempty"`
+}
+
+var ErrNotOurShim = errors.New("app.asar was not written by the Vencord Installer")
+
+// Shims written before metadata was added only contain require("<Patcher>")
+var legacyShimRe = regexp.MustCompile(`^require\(("(?:[^"\\]|\\.)*")\)$`)
+
+// The shim loads the patcher, but falls back to stock Discord if the patcher is missing or throws,
+// for example because the dist folder was deleted or a Flatpak lacks the filesystem override.
+// If the patcher threw after it already started the original app, it is not started a second time
+const shimIndexJs = `const { join } = require("path");
+const { accessSync, constants } = require("fs");
+
+const patcher = %s;
+
+try {
+    accessSync(patcher, constants.R_OK);
+    require(patcher);
+} catch (err) {
+    console.error("[Vencord] Failed to load " + patcher + ". Starting Discord without Vencord.", err);
 
-	fileContents := ""
+    const asarPath = join(__dirname, "..", "_app.asar");
+    const pkg = require(join(asarPath, "package.json"));
+    const main = join(asarPath, pkg.main);
 
+    if (!require.cache[require.resolve(main)]) {
+        require("electron").app.setAppPath(asarPath);
+        require.main.filename = main;
+        require(main);
+    }
+}
+`
+
+func WriteAppAsar(outFile string, patcherPath string) error {
 	patcherPathB, _ := json.Marshal(patcherPath)
-	indexJsContents := "require(" + string(patcherPathB) + ")"
-	indexJsBytes := len([]byte(indexJsContents))
-	fileContents += indexJsContents
-	files["index.js"] = asarEntry{
-		Size:   int32(indexJsBytes),
-		Offset: "0",
+	indexJsContents := fmt.Sprintf(shimIndexJs, patcherPathB)
+
+	packageJson, err := json.MarshalIndent(shimPackageJson{
+		Name: "discord",
+		Main: "index.js",
+		VencordInstaller: &ShimMetadata{
+			InstallerVersion: buildinfo.InstallerTag,
+			InstallerGitHash: buildinfo.InstallerGitHash,
+			PatchedAt:        time.Now().UTC(),
+			Patcher:          patcherPath,
+		},
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	packer, err := NewAsarPacker(outFile)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range [][]string{{"index.js", indexJsContents}, {"package.json", string(packageJson)}} {
+		if err = packer.AddFile(file[0], strings.NewReader(file[1]), AsarFileOptions{}); err != nil {
+			packer.Abort()
+			return err
+		}
+	}
+
+	return packer.Close()
+}
+
+// ReadShimMetadata returns the metadata of the shim at asarFile, or ErrNotOurShim if it is not one
+func ReadShimMetadata(asarFile string) (*ShimMetadata, error) {
+	a, err := OpenAsar(asarFile)
+	if err != nil {
+		return nil, err
 	}
+	defer a.Close()
 
-	fileContents += PackageJson
-	files["package.json"] = asarEntry{
-		Size:   int32(len([]byte(PackageJson))),
-		Offset: strconv.Itoa(indexJsBytes),
+	b, err := a.ReadFile("package.json")
+	if err != nil {
+		return nil, ErrNotOurShim
 	}
 
-	headerBytes, _ := json.Marshal(header)
-	headerString := string(headerBytes)
-	headerStringSize := uint32(len(headerString))
-	dataSize := uint32(4)
-	alignedSize := (headerStringSize + dataSize - 1) & ^(dataSize - 1)
-	headerSize := alignedSize + 8
-	headerObjectSize := alignedSize + dataSize
-	diff := alignedSize - headerStringSize
-	if diff > 0 {
-		headerString += strings.Repeat("0", int(diff))
+	var pkg shimPackageJson
+	if err = json.Unmarshal(b, &pkg); err != nil || pkg.Name != "discord" || pkg.Main != "index.js" {
+		return nil, ErrNotOurShim
+	}
+	if pkg.VencordInstaller != nil {
+		return pkg.VencordInstaller, nil
 	}
 
-	f, err := os.Create(outFile)
+	indexJs, err := a.ReadFile("index.js")
 	if err != nil {
-		return fmt.Errorf("Failed to create %s: %w", outFile, err)
+		return nil, ErrNotOurShim
+	}
+	match := legacyShimRe.FindSubmatch(indexJs)
+	if match == nil {
+		return nil, ErrNotOurShim
 	}
-	defer f.Close()
 
-	for _, n := range []uint32{dataSize, headerSize, headerObjectSize, headerStringSize} {
-		if err = binary.Write(f, binary.LittleEndian, int32(n)); err != nil {
-			return fmt.Errorf("Failed to write asar bytes: %w", err)
-		}
+	meta := &ShimMetadata{}
+	if err = json.Unmarshal(match[1], &meta.Patcher); err != nil {
+		return nil, ErrNotOurShim
 	}
+	return meta, nil
+}
 
-	for _, s := range []string{headerString, fileContents} {
-		if _, err = f.WriteString(s); err != nil {
-			return fmt.Errorf("Failed to write asar data: %w", err)
-		}
+func DetectPatchState(resourcesDir string) (PatchState, *ShimMetadata) {
+	if !ExistsFile(path.Join(resourcesDir, "_app.asar")) {
+		return PatchStateUnpatched, nil
+	}
+
+	meta, err := ReadShimMetadata(path.Join(resourcesDir, "app.asar"))
+	if err != nil {
+		Log.Debug("Install at", resourcesDir, "is patched, but not by us:", err)
+		return PatchStateForeign, nil
+	}
+
+	if path.Clean(meta.Patcher) != path.Clean(Patcher) {
+		Log.Debug("Install at", resourcesDir, "loads", meta.Patcher, "instead of", Patcher)
+		return PatchStateStalePatcher, meta
+	}
+	if _, err = os.Stat(meta.Patcher); err != nil {
+		Log.Debug("Install at", resourcesDir, "loads missing patcher", meta.Patcher)
+		return PatchStateStalePatcher, meta
 	}
 
-	return nil
+	return PatchStateVencord, meta
 }
@@ -0,0 +1,442 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format reference: https://github.com/electron/asar#format
+
+type AsarIntegrity struct {
+	Algorithm string   `json:"algorithm"`
+	Hash      string   `json:"hash"`
+	BlockSize int      `json:"blockSize"`
+	Blocks    []string `json:"blocks"`
+}
+
+type AsarEntry struct {
+	Files      map[string]*AsarEntry // nil unless this is a directory
+	Size       int64
+	Offset     int64
+	Unpacked   bool
+	Executable bool
+	Link       string // target relative to the archive root, empty unless this is a symlink
+	Integrity  *AsarIntegrity
+}
+
+type asarRawEntry struct {
+	Files      map[string]*AsarEntry `json:"files"`
+	Size       int64                 `json:"size"`
+	Offset     json.Number           `json:"offset"`
+	Unpacked   bool                  `json:"unpacked"`
+	Executable bool                  `json:"executable"`
+	Link       string                `json:"link"`
+	Integrity  *AsarIntegrity        `json:"integrity"`
+}
+
+func (e *AsarEntry) UnmarshalJSON(b []byte) error {
+	var raw asarRawEntry
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	// Offsets are strings because JavaScript numbers can't represent 64 bit integers
+	var offset int64
+	if raw.Offset != "" {
+		var err error
+		if offset, err = raw.Offset.Int64(); err != nil {
+			return fmt.Errorf("Invalid asar offset %q: %w", raw.Offset, err)
+		}
+	}
+
+	*e = AsarEntry{
+		Files:      raw.Files,
+		Size:       raw.Size,
+		Offset:     offset,
+		Unpacked:   raw.Unpacked,
+		Executable: raw.Executable,
+		Link:       raw.Link,
+		Integrity:  raw.Integrity,
+	}
+	return nil
+}
+
+func (e *AsarEntry) MarshalJSON() ([]byte, error) {
+	if e.IsDir() {
+		return json.Marshal(struct {
+			Files map[string]*AsarEntry `json:"files"`
+		}{e.Files})
+	}
+	if e.IsLink() {
+		return json.Marshal(struct {
+			Link string `json:"link"`
+		}{e.Link})
+	}
+
+	// Unlike the other fields, size is also required for empty files
+	file := struct {
+		Size       int64          `json:"size"`
+		Offset     string         `json:"offset,omitempty"`
+		Unpacked   bool           `json:"unpacked,omitempty"`
+		Executable bool           `json:"executable,omitempty"`
+		Integrity  *AsarIntegrity `json:"integrity,omitempty"`
+	}{
+		Size:       e.Size,
+		Unpacked:   e.Unpacked,
+		Executable: e.Executable,
+		Integrity:  e.Integrity,
+	}
+	if !e.Unpacked {
+		file.Offset = strconv.FormatInt(e.Offset, 10)
+	}
+	return json.Marshal(file)
+}
+
+func (e *AsarEntry) IsDir() bool {
+	return e.Files != nil
+}
+
+func (e *AsarEntry) IsLink() bool {
+	return e.Link != ""
+}
+
+type AsarArchive struct {
+	Path       string
+	Header     *AsarEntry
+	HeaderSize int64 // size of the JSON header in bytes
+	dataOffset int64
+	file       *os.File
+}
+
+func OpenAsar(p string) (*AsarArchive, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := readAsarHeader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("Failed to read asar header of %s: %w", p, err)
+	}
+	a.Path = p
+	return a, nil
+}
+
+func readAsarHeader(f *os.File) (*AsarArchive, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var sizePickle [2]uint32
+	if err = binary.Read(f, binary.LittleEndian, &sizePickle); err != nil {
+		return nil, err
+	}
+	if sizePickle[0] != 4 {
+		return nil, errors.New("not an asar archive")
+	}
+
+	headerPickleSize := int64(sizePickle[1])
+	if headerPickleSize < 8 || 8+headerPickleSize > stat.Size() {
+		return nil, fmt.Errorf("invalid header size %d", headerPickleSize)
+	}
+
+	headerPickle := make([]byte, headerPickleSize)
+	if _, err = io.ReadFull(f, headerPickle); err != nil {
+		return nil, err
+	}
+
+	// headerPickle[0:4] is the pickle payload size, headerPickle[4:8] the string length
+	jsonSize := int64(binary.LittleEndian.Uint32(headerPickle[4:8]))
+	if 8+jsonSize > headerPickleSize {
+		return nil, fmt.Errorf("invalid header string size %d", jsonSize)
+	}
+
+	var header AsarEntry
+	if err = json.Unmarshal(headerPickle[8:8+jsonSize], &header); err != nil {
+		return nil, err
+	}
+	if !header.IsDir() {
+		return nil, errors.New("header has no files")
+	}
+
+	return &AsarArchive{
+		Header:     &header,
+		HeaderSize: jsonSize,
+		dataOffset: 8 + headerPickleSize,
+		file:       f,
+	}, nil
+}
+
+func (a *AsarArchive) Close() error {
+	return a.file.Close()
+}
+
+func splitAsarPath(p string) []string {
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(p, "\\", "/"), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// resolve returns the entry at p and its canonical path inside the archive, following symlinks
+func (a *AsarArchive) resolve(p string) (*AsarEntry, string, error) {
+	return a.resolveDepth(p, 0)
+}
+
+func (a *AsarArchive) resolveDepth(p string, depth int) (*AsarEntry, string, error) {
+	if depth > 40 {
+		return nil, "", fmt.Errorf("%s: too many levels of symbolic links", p)
+	}
+
+	entry := a.Header
+	var resolved []string
+	for _, part := range splitAsarPath(p) {
+		if part == ".." {
+			return nil, "", fmt.Errorf("%s: path escapes the archive", p)
+		}
+		if !entry.IsDir() {
+			return nil, "", fmt.Errorf("%s: %w", p, fs.ErrNotExist)
+		}
+
+		child, ok := entry.Files[part]
+		if !ok {
+			return nil, "", fmt.Errorf("%s: %w", p, fs.ErrNotExist)
+		}
+		resolved = append(resolved, part)
+
+		if child.IsLink() {
+			target, targetPath, err := a.resolveDepth(child.Link, depth+1)
+			if err != nil {
+				return nil, "", err
+			}
+			child = target
+			resolved = splitAsarPath(targetPath)
+		}
+		entry = child
+	}
+
+	return entry, strings.Join(resolved, "/"), nil
+}
+
+func (a *AsarArchive) Stat(p string) (*AsarEntry, error) {
+	entry, _, err := a.resolve(p)
+	return entry, err
+}
+
+func (a *AsarArchive) Exists(p string) bool {
+	_, err := a.Stat(p)
+	return err == nil
+}
+
+// Walk calls fn for every entry in the archive in lexical order. Symlinks are reported but not followed
+func (a *AsarArchive) Walk(fn func(p string, e *AsarEntry) error) error {
+	return walkAsarEntry("", a.Header, fn)
+}
+
+func walkAsarEntry(dir string, e *AsarEntry, fn func(p string, e *AsarEntry) error) error {
+	for _, name := range SortedKeys(e.Files) {
+		child := e.Files[name]
+		p := path.Join(dir, name)
+		if err := fn(p, child); err != nil {
+			return err
+		}
+		if child.IsDir() {
+			if err := walkAsarEntry(p, child, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *AsarArchive) List() []string {
+	var paths []string
+	_ = a.Walk(func(p string, _ *AsarEntry) error {
+		paths = append(paths, p)
+		return nil
+	})
+	return paths
+}
+
+func (a *AsarArchive) Open(p string) (io.ReadCloser, error) {
+	entry, resolved, err := a.resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir() {
+		return nil, fmt.Errorf("%s: is a directory", p)
+	}
+
+	if entry.Unpacked {
+		return os.Open(filepath.Join(a.Path+".unpacked", filepath.FromSlash(resolved)))
+	}
+
+	return io.NopCloser(io.NewSectionReader(a.file, a.dataOffset+entry.Offset, entry.Size)), nil
+}
+
+func (a *AsarArchive) ReadFile(p string) ([]byte, error) {
+	r, err := a.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Extract writes the file or directory at p to dest. Use an empty p to extract the whole archive
+func (a *AsarArchive) Extract(p, dest string) error {
+	p = strings.Join(splitAsarPath(p), "/")
+
+	entry := a.Header
+	if p != "" {
+		var err error
+		if entry, err = a.Stat(p); err != nil {
+			return err
+		}
+	}
+
+	if !entry.IsDir() {
+		return a.extractFile(p, entry, dest)
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	return walkAsarEntry(p, entry, func(childPath string, child *AsarEntry) error {
+		rel := strings.TrimPrefix(childPath, p)
+		out := filepath.Join(dest, filepath.FromSlash(rel))
+
+		switch {
+		case child.IsLink():
+			// asar links are relative to the archive root, symlinks to the directory they are in
+			target, err := filepath.Rel(filepath.FromSlash(path.Dir(childPath)), filepath.FromSlash(child.Link))
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, out)
+		case child.IsDir():
+			return os.MkdirAll(out, 0755)
+		default:
+			return a.extractFile(childPath, child, out)
+		}
+	})
+}
+
+func (a *AsarArchive) extractFile(p string, entry *AsarEntry, dest string) error {
+	r, err := a.Open(p)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, Ternary[os.FileMode](entry.Executable, 0755, 0644))
+	if err != nil {
+		return fmt.Errorf("Failed to create %s: %w", dest, err)
+	}
+
+	if _, err = io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("Failed to extract %s: %w", p, err)
+	}
+	return out.Close()
+}
+
+const AsarIntegrityBlockSize = 4 * 1024 * 1024
+
+// asarIntegrityHasher computes the same hashes as @electron/asar: a SHA256 of the whole file plus one per 4MiB block.
+// Like upstream, the final (possibly empty) block is always hashed, so empty files still have one block
+type asarIntegrityHasher struct {
+	file      hash.Hash
+	block     hash.Hash
+	blockSize int
+	blocks    []string
+}
+
+func newAsarIntegrityHasher() *asarIntegrityHasher {
+	return &asarIntegrityHasher{file: sha256.New(), block: sha256.New()}
+}
+
+func (h *asarIntegrityHasher) Write(b []byte) (int, error) {
+	n := len(b)
+	h.file.Write(b)
+	for len(b) > 0 {
+		chunk := AsarIntegrityBlockSize - h.blockSize
+		if chunk > len(b) {
+			chunk = len(b)
+		}
+		h.block.Write(b[:chunk])
+		h.blockSize += chunk
+		b = b[chunk:]
+
+		if h.blockSize == AsarIntegrityBlockSize {
+			h.blocks = append(h.blocks, hex.EncodeToString(h.block.Sum(nil)))
+			h.block.Reset()
+			h.blockSize = 0
+		}
+	}
+	return n, nil
+}
+
+func (h *asarIntegrityHasher) Integrity() *AsarIntegrity {
+	blocks := append(h.blocks[:len(h.blocks):len(h.blocks)], hex.EncodeToString(h.block.Sum(nil)))
+	return &AsarIntegrity{
+		Algorithm: "SHA256",
+		Hash:      hex.EncodeToString(h.file.Sum(nil)),
+		BlockSize: AsarIntegrityBlockSize,
+		Blocks:    blocks,
+	}
+}
+
+// VerifyIntegrity checks the contents of the file at p against the hashes stored in the header.
+// Files without integrity information are considered valid
+func (a *AsarArchive) VerifyIntegrity(p string) error {
+	entry, err := a.Stat(p)
+	if err != nil {
+		return err
+	}
+	if entry.Integrity == nil {
+		return nil
+	}
+	if entry.Integrity.Algorithm != "SHA256" || entry.Integrity.BlockSize != AsarIntegrityBlockSize {
+		return fmt.Errorf("%s: unsupported integrity algorithm %s with block size %d", p, entry.Integrity.Algorithm, entry.Integrity.BlockSize)
+	}
+
+	r, err := a.Open(p)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	h := newAsarIntegrityHasher()
+	if _, err = io.Copy(h, r); err != nil {
+		return err
+	}
+
+	if actual := h.Integrity(); actual.Hash != entry.Integrity.Hash {
+		return fmt.Errorf("%s: integrity hash mismatch. Expected %s, got %s", p, entry.Integrity.Hash, actual.Hash)
+	}
+	return nil
+}
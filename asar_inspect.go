@@ -0,0 +1,108 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Top level .js and .json files up to this size have their contents printed
+const inspectMaxContentSize = 16 * 1024
+
+func InspectAsar(w io.Writer, file string) error {
+	a, err := OpenAsar(file)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	_, _ = fmt.Fprintln(w, file)
+	_, _ = fmt.Fprintf(w, "Header: %d bytes, file data starts at byte %d\n\n", a.HeaderSize, a.dataOffset)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PATH\tSIZE\tOFFSET\tFLAGS")
+	err = a.Walk(func(p string, e *AsarEntry) error {
+		name := strings.Repeat("  ", strings.Count(p, "/")) + path.Base(p)
+		switch {
+		case e.IsLink():
+			_, err := fmt.Fprintf(tw, "%s -> %s\t\t\tlink\n", name, e.Link)
+			return err
+		case e.IsDir():
+			_, err := fmt.Fprintf(tw, "%s/\t\t\t\n", name)
+			return err
+		}
+
+		var flags []string
+		offset := strconv.FormatInt(e.Offset, 10)
+		if e.Unpacked {
+			flags = append(flags, "unpacked")
+			offset = "-"
+		}
+		if e.Executable {
+			flags = append(flags, "executable")
+		}
+		if e.Integrity != nil {
+			flags = append(flags, "integrity")
+		}
+		_, err := fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", name, e.Size, offset, strings.Join(flags, ","))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, name := range SortedKeys(a.Header.Files) {
+		e := a.Header.Files[name]
+		ext := path.Ext(name)
+		if e.IsDir() || e.Size > inspectMaxContentSize || (ext != ".js" && ext != ".json") {
+			continue
+		}
+
+		b, err := a.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "\n--- %s ---\n%s\n", name, strings.TrimRight(string(b), "\n"))
+	}
+
+	return nil
+}
+
+// InspectInstallAsars inspects both the app.asar and the _app.asar of di, if present
+func InspectInstallAsars(w io.Writer, di *DiscordInstall) error {
+	found := false
+	for _, name := range []string{"app.asar", "_app.asar"} {
+		file := filepath.Join(di.resourcesDir(), name)
+		if !ExistsFile(file) || IsDirectory(file) {
+			continue
+		}
+
+		if found {
+			_, _ = fmt.Fprintln(w)
+		}
+		found = true
+
+		if err := InspectAsar(w, file); err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		return errors.New("Install at " + di.path + " has no asar file")
+	}
+	return nil
+}
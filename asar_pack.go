@@ -0,0 +1,267 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type AsarFileOptions struct {
+	Unpacked   bool // store the file in <archive>.unpacked instead of the archive itself
+	Executable bool
+}
+
+// AsarPacker builds an asar archive. The header has to come before the file contents but depends on them,
+// so packed contents are spooled to a temporary file and only copied into the archive on Close
+type AsarPacker struct {
+	outFile string
+	header  *AsarEntry
+	data    *os.File
+	size    int64
+}
+
+func NewAsarPacker(outFile string) (*AsarPacker, error) {
+	data, err := os.CreateTemp("", "VencordAsar")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create tempfile: %w", err)
+	}
+
+	return &AsarPacker{
+		outFile: outFile,
+		header:  &AsarEntry{Files: map[string]*AsarEntry{}},
+		data:    data,
+	}, nil
+}
+
+func (p *AsarPacker) mkdirAll(parts []string) (*AsarEntry, error) {
+	dir := p.header
+	for i, part := range parts {
+		child, ok := dir.Files[part]
+		if !ok {
+			child = &AsarEntry{Files: map[string]*AsarEntry{}}
+			dir.Files[part] = child
+		} else if !child.IsDir() {
+			return nil, fmt.Errorf("%s: not a directory", strings.Join(parts[:i+1], "/"))
+		}
+		dir = child
+	}
+	return dir, nil
+}
+
+// addEntry inserts entry at name, creating parent directories as needed
+func (p *AsarPacker) addEntry(name string, entry *AsarEntry) error {
+	parts := splitAsarPath(name)
+	if len(parts) == 0 {
+		return errors.New("Invalid asar path " + name)
+	}
+	if SliceContains(parts, "..") {
+		return errors.New("Asar path " + name + " escapes the archive")
+	}
+
+	dir, err := p.mkdirAll(parts[:len(parts)-1])
+	if err != nil {
+		return err
+	}
+
+	base := parts[len(parts)-1]
+	if _, exists := dir.Files[base]; exists {
+		return fmt.Errorf("%s: %w", name, fs.ErrExist)
+	}
+	dir.Files[base] = entry
+	return nil
+}
+
+func (p *AsarPacker) AddDir(name string) error {
+	parts := splitAsarPath(name)
+	if SliceContains(parts, "..") {
+		return errors.New("Asar path " + name + " escapes the archive")
+	}
+	_, err := p.mkdirAll(parts)
+	return err
+}
+
+// AddLink adds a symlink. target is relative to the archive root
+func (p *AsarPacker) AddLink(name, target string) error {
+	return p.addEntry(name, &AsarEntry{Link: strings.Join(splitAsarPath(target), "/")})
+}
+
+func (p *AsarPacker) AddFile(name string, r io.Reader, opts AsarFileOptions) error {
+	entry := &AsarEntry{
+		Unpacked:   opts.Unpacked,
+		Executable: opts.Executable,
+	}
+	if err := p.addEntry(name, entry); err != nil {
+		return err
+	}
+
+	h := newAsarIntegrityHasher()
+
+	if opts.Unpacked {
+		outFile := filepath.Join(p.outFile+".unpacked", filepath.FromSlash(strings.Join(splitAsarPath(name), "/")))
+		if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
+			return err
+		}
+
+		out, err := os.OpenFile(outFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, Ternary[os.FileMode](opts.Executable, 0755, 0644))
+		if err != nil {
+			return fmt.Errorf("Failed to create %s: %w", outFile, err)
+		}
+
+		entry.Size, err = io.Copy(io.MultiWriter(out, h), r)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to write %s: %w", outFile, err)
+		}
+	} else {
+		n, err := io.Copy(io.MultiWriter(p.data, h), r)
+		if err != nil {
+			return fmt.Errorf("Failed to write asar data: %w", err)
+		}
+		entry.Offset = p.size
+		entry.Size = n
+		p.size += n
+	}
+
+	entry.Integrity = h.Integrity()
+	return nil
+}
+
+// AddFromDisk recursively adds the directory src to the archive at dest.
+// unpack decides which files go into <archive>.unpacked and may be nil
+func (p *AsarPacker) AddFromDisk(src, dest string, unpack func(p string) bool) error {
+	// links are resolved fully, so src must be too, or every link would seem to point outside of it
+	root, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		name := path.Join(dest, filepath.ToSlash(rel))
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := filepath.EvalSymlinks(file)
+			if err != nil {
+				return err
+			}
+			relTarget, err := filepath.Rel(root, target)
+			if err != nil || relTarget == ".." || strings.HasPrefix(relTarget, ".."+string(filepath.Separator)) {
+				return fmt.Errorf("%s links outside of %s", file, src)
+			}
+			return p.AddLink(name, path.Join(dest, filepath.ToSlash(relTarget)))
+		case d.IsDir():
+			return p.AddDir(name)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return p.AddFile(name, f, AsarFileOptions{
+			Unpacked:   unpack != nil && unpack(name),
+			Executable: info.Mode()&0111 != 0,
+		})
+	})
+}
+
+// AddFromAsar copies every entry of a into the archive at dest, keeping unpacked files unpacked
+func (p *AsarPacker) AddFromAsar(a *AsarArchive, dest string) error {
+	return a.Walk(func(name string, e *AsarEntry) error {
+		out := path.Join(dest, name)
+		switch {
+		case e.IsLink():
+			return p.AddLink(out, path.Join(dest, e.Link))
+		case e.IsDir():
+			return p.AddDir(out)
+		}
+
+		r, err := a.Open(name)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		return p.AddFile(out, r, AsarFileOptions{
+			Unpacked:   e.Unpacked,
+			Executable: e.Executable,
+		})
+	})
+}
+
+// Close writes the archive to disk. The packer must not be used afterwards
+func (p *AsarPacker) Close() (err error) {
+	defer p.Abort()
+
+	headerJson, err := json.Marshal(p.header)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal asar header: %w", err)
+	}
+
+	// Header layout ported from https://github.com/GeopJr/asar-cr/blob/cd7695b7c913bf921d9fb6600eaeb1400e3ba225/src/asar-cr/pack.cr#L61
+	// The second pickle's string is padded to a multiple of 4 bytes
+	jsonSize := uint32(len(headerJson))
+	alignedSize := (jsonSize + 3) &^ 3
+	sizes := []uint32{4, alignedSize + 8, alignedSize + 4, jsonSize}
+
+	f, err := os.Create(p.outFile)
+	if err != nil {
+		return fmt.Errorf("Failed to create %s: %w", p.outFile, err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("Failed to write %s: %w", p.outFile, closeErr)
+		}
+	}()
+
+	if err = binary.Write(f, binary.LittleEndian, sizes); err != nil {
+		return fmt.Errorf("Failed to write asar bytes: %w", err)
+	}
+	if _, err = f.Write(append(headerJson, make([]byte, alignedSize-jsonSize)...)); err != nil {
+		return fmt.Errorf("Failed to write asar header: %w", err)
+	}
+
+	if _, err = p.data.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, p.data); err != nil {
+		return fmt.Errorf("Failed to write asar data: %w", err)
+	}
+
+	return nil
+}
+
+// Abort discards the spooled file contents without writing the archive
+func (p *AsarPacker) Abort() {
+	_ = p.data.Close()
+	_ = os.Remove(p.data.Name())
+}
@@ -0,0 +1,317 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAsarPackRoundTrip(t *testing.T) {
+	files := map[string][]byte{
+		"package.json":       []byte(`{"name":"discord","main":"index.js"}`),
+		"index.js":           []byte("require('./lib/util.js')"),
+		"lib/util.js":        []byte("module.exports = {}"),
+		"lib/deep/empty.txt": {},
+		// more than one integrity block
+		"big.bin": bytes.Repeat([]byte("vencord"), AsarIntegrityBlockSize/7+100),
+	}
+	native := []byte("\x7fELF not really")
+
+	file := filepath.Join(t.TempDir(), "app.asar")
+	p, err := NewAsarPacker(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range SortedKeys(files) {
+		if err = p.AddFile(name, bytes.NewReader(files[name]), AsarFileOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = p.AddFile("native/addon.node", bytes.NewReader(native), AsarFileOptions{Unpacked: true, Executable: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddLink("util.js", "lib/util.js"); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddDir("empty"); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFile("index.js", strings.NewReader("duplicate"), AsarFileOptions{}); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("adding index.js twice returned %v, want ErrExist", err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenAsar(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	for name, want := range files {
+		got, err := a.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("ReadFile(%s) returned %d bytes, want %d", name, len(got), len(want))
+		}
+		if err = a.VerifyIntegrity(name); err != nil {
+			t.Errorf("VerifyIntegrity(%s): %v", name, err)
+		}
+	}
+
+	entry, err := a.Stat("native/addon.node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Unpacked || !entry.Executable {
+		t.Errorf("native/addon.node is unpacked=%v executable=%v, want both", entry.Unpacked, entry.Executable)
+	}
+	if got, err := a.ReadFile("native/addon.node"); err != nil || !bytes.Equal(got, native) {
+		t.Errorf("ReadFile(native/addon.node) = %q, %v", got, err)
+	}
+	if err = a.VerifyIntegrity("native/addon.node"); err != nil {
+		t.Errorf("VerifyIntegrity(native/addon.node): %v", err)
+	}
+
+	if got, err := a.ReadFile("util.js"); err != nil || !bytes.Equal(got, files["lib/util.js"]) {
+		t.Errorf("ReadFile(util.js) = %q, %v, want the contents of lib/util.js", got, err)
+	}
+	if entry, err = a.Stat("empty"); err != nil || !entry.IsDir() {
+		t.Errorf("Stat(empty) = %v, %v, want a directory", entry, err)
+	}
+	if a.Exists("missing.js") {
+		t.Error("Exists(missing.js) = true")
+	}
+
+	want := "big.bin empty index.js lib lib/deep lib/deep/empty.txt lib/util.js native native/addon.node package.json util.js"
+	if got := strings.Join(a.List(), " "); got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+}
+
+func TestAsarExtract(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.asar")
+	p, err := NewAsarPacker(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFile("lib/util.js", strings.NewReader("module.exports = {}"), AsarFileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFile("lib/native.node", strings.NewReader("native"), AsarFileOptions{Unpacked: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenAsar(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	out := filepath.Join(dir, "out")
+	if err = a.Extract("", out); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"lib/util.js": "module.exports = {}", "lib/native.node": "native"} {
+		got, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("extracted %s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
+
+// asarLinks returns the link targets of all symlinks in a
+func asarLinks(t *testing.T, a *AsarArchive) map[string]string {
+	links := make(map[string]string)
+	if err := a.Walk(func(name string, e *AsarEntry) error {
+		if e.IsLink() {
+			links[name] = e.Link
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return links
+}
+
+func TestAsarAddFromDisk(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs privileges on windows")
+	}
+
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	writeTestFile(t, filepath.Join(real, "index.js"), "require('./lib/util.js')")
+	writeTestFile(t, filepath.Join(real, "lib", "util.js"), "module.exports = {}")
+	writeTestFile(t, filepath.Join(real, "native", "addon.node"), "native")
+	if err := os.Chmod(filepath.Join(real, "native", "addon.node"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("lib", "util.js"), filepath.Join(real, "util.js")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("lib", filepath.Join(real, "lib2")); err != nil {
+		t.Fatal(err)
+	}
+	// src itself is a link, so the resolved link targets are not below it
+	src := filepath.Join(dir, "src")
+	if err := os.Symlink(real, src); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "app.asar")
+	p, err := NewAsarPacker(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFromDisk(src, "app", func(name string) bool { return strings.HasSuffix(name, ".node") }); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenAsar(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	for name, want := range map[string]string{
+		"app/index.js":          "require('./lib/util.js')",
+		"app/util.js":           "module.exports = {}",
+		"app/lib2/util.js":      "module.exports = {}",
+		"app/native/addon.node": "native",
+	} {
+		if got, err := a.ReadFile(name); err != nil || string(got) != want {
+			t.Errorf("ReadFile(%s) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	links := asarLinks(t, a)
+	if links["app/util.js"] != "app/lib/util.js" || links["app/lib2"] != "app/lib" {
+		t.Errorf("links = %v, want app/util.js -> app/lib/util.js and app/lib2 -> app/lib", links)
+	}
+	entry, err := a.Stat("app/native/addon.node")
+	if err != nil || !entry.Unpacked || !entry.Executable {
+		t.Errorf("Stat(app/native/addon.node) = %+v, %v, want unpacked and executable", entry, err)
+	}
+	if entry, err = a.Stat("app/index.js"); err != nil || entry.Unpacked || entry.Executable {
+		t.Errorf("Stat(app/index.js) = %+v, %v, want neither unpacked nor executable", entry, err)
+	}
+}
+
+func TestAsarAddFromDiskRejectsLinksOutside(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs privileges on windows")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	writeTestFile(t, filepath.Join(dir, "secret"), "secret")
+	writeTestFile(t, filepath.Join(src, "index.js"), "")
+	if err := os.Symlink(filepath.Join("..", "secret"), filepath.Join(src, "secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewAsarPacker(filepath.Join(dir, "app.asar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if err = p.AddFromDisk(src, "", nil); err == nil || !strings.Contains(err.Error(), "links outside") {
+		t.Errorf("AddFromDisk() = %v, want an error about the link", err)
+	}
+}
+
+func TestAsarAddFromAsar(t *testing.T) {
+	dir := t.TempDir()
+	orig := filepath.Join(dir, "orig.asar")
+	p, err := NewAsarPacker(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFile("lib/util.js", strings.NewReader("module.exports = {}"), AsarFileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFile("native/addon.node", strings.NewReader("native"), AsarFileOptions{Unpacked: true, Executable: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddLink("util.js", "lib/util.js"); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddDir("empty"); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := OpenAsar(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	file := filepath.Join(dir, "app.asar")
+	if p, err = NewAsarPacker(file); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFile("index.js", strings.NewReader("require('./orig')"), AsarFileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFromAsar(src, "orig"); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenAsar(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	for name, want := range map[string]string{
+		"index.js":               "require('./orig')",
+		"orig/lib/util.js":       "module.exports = {}",
+		"orig/util.js":           "module.exports = {}",
+		"orig/native/addon.node": "native",
+	} {
+		if got, err := a.ReadFile(name); err != nil || string(got) != want {
+			t.Errorf("ReadFile(%s) = %q, %v, want %q", name, got, err, want)
+		}
+		if err = a.VerifyIntegrity(name); err != nil {
+			t.Errorf("VerifyIntegrity(%s): %v", name, err)
+		}
+	}
+	if links := asarLinks(t, a); links["orig/util.js"] != "orig/lib/util.js" {
+		t.Errorf("links = %v, want orig/util.js -> orig/lib/util.js", links)
+	}
+	entry, err := a.Stat("orig/native/addon.node")
+	if err != nil || !entry.Unpacked || !entry.Executable {
+		t.Errorf("Stat(orig/native/addon.node) = %+v, %v, want unpacked and executable", entry, err)
+	}
+	assertFile(t, filepath.Join(file+".unpacked", "orig", "native", "addon.node"), "native")
+	if entry, err = a.Stat("orig/empty"); err != nil || !entry.IsDir() {
+		t.Errorf("Stat(orig/empty) = %+v, %v, want a directory", entry, err)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	path "path/filepath"
+	"strings"
+)
+
+// Releases can list their assets in AssetManifestName. Releases without one use DefaultAssetManifest
+
+const AssetManifestName = "installer-assets.json"
+
+type AssetRule struct {
+	Name     string   `json:"name"`
+	Required bool     `json:"required,omitempty"`
+	Urls     []string `json:"urls,omitempty"`
+}
+
+type AssetManifest struct {
+	Assets  []AssetRule `json:"assets"`
+	Cleanup []string    `json:"cleanup"`
+}
+
+var DefaultAssetManifest = AssetManifest{
+	Assets: []AssetRule{
+		{Name: "patcher.js", Required: true},
+		{Name: "preload.js", Required: true},
+		{Name: "renderer.js", Required: true},
+		{Name: "renderer.css", Required: true},
+		{Name: "*.map"},
+		{Name: "*.LEGAL.txt"},
+		{Name: "LICENSE*"},
+	},
+	Cleanup: []string{"*.js", "*.css", "*.map", "*.LEGAL.txt", "LICENSE*"},
+}
+
+// VencordAssets is the manifest of the build being installed
+var VencordAssets = &DefaultAssetManifest
+
+func ParseAssetManifest(b []byte) (*AssetManifest, error) {
+	m := &AssetManifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range m.Assets {
+		// assets are written to <staging>/<name>, so they must not be able to point anywhere else
+		if _, err := path.Match(rule.Name, ""); err != nil || rule.Name == "" || rule.Name == "." || rule.Name == ".." || strings.ContainsAny(rule.Name, `/\`) {
+			return nil, fmt.Errorf("Invalid asset name %q", rule.Name)
+		}
+		if rule.Required && strings.ContainsAny(rule.Name, `*?[\`) {
+			return nil, fmt.Errorf("Required asset %s must not be a pattern", rule.Name)
+		}
+	}
+	for _, pattern := range m.Cleanup {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid cleanup pattern %q", pattern)
+		}
+	}
+	// everything else relies on it, from the version header to recovering interrupted updates
+	if !SliceContains(m.RequiredFiles(), "patcher.js") {
+		return nil, errors.New("patcher.js must be a required asset")
+	}
+	return m, nil
+}
+
+// FetchAssetManifest fetches the asset manifest of release, or returns DefaultAssetManifest if it has none
+func FetchAssetManifest(ctx context.Context, release *GithubRelease, checksums Checksums) (*AssetManifest, error) {
+	i := SliceIndexFunc(release.Assets, func(ass GithubAsset) bool { return ass.Name == AssetManifestName })
+	if i == -1 {
+		return &DefaultAssetManifest, nil
+	}
+
+	ass := release.Assets[i]
+	Log.Debug("Fetching asset manifest", ass.Name)
+	b, err := downloadBytesFromMirrors(ctx, assetUrls(release, ass))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch asset manifest %s: %w", ass.Name, err)
+	}
+	if err = verifyAssetBytes(ctx, release, ass.Name, b, checksums); err != nil {
+		return nil, err
+	}
+
+	m, err := ParseAssetManifest(b)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid asset manifest %s: %w", ass.Name, err)
+	}
+	return m, nil
+}
+
+// Includes reports whether name is one of the assets to install
+func (m *AssetManifest) Includes(name string) bool {
+	if strings.HasSuffix(name, ".sig") || name == AssetManifestName || SliceContains(ChecksumManifestNames, name) {
+		return false
+	}
+	return SliceContainsFunc(m.Assets, func(rule AssetRule) bool {
+		ok, _ := path.Match(rule.Name, name)
+		return ok
+	})
+}
+
+// FallbackUrls returns the fallback urls of the first rule matching name
+func (m *AssetManifest) FallbackUrls(name string) []string {
+	for _, rule := range m.Assets {
+		if ok, _ := path.Match(rule.Name, name); ok {
+			return append([]string(nil), rule.Urls...)
+		}
+	}
+	return nil
+}
+
+func (m *AssetManifest) RequiredFiles() []string {
+	required := SliceFilter(m.Assets, func(rule AssetRule) bool { return rule.Required })
+	return SliceMap(required, func(rule AssetRule) string { return rule.Name })
+}
+
+// MissingFiles returns the required assets that aren't in names
+func (m *AssetManifest) MissingFiles(names []string) []string {
+	return SliceFilter(m.RequiredFiles(), func(name string) bool { return !SliceContains(names, name) })
+}
+
+// SelectAssets returns the assets to install, or an error if required ones are missing
+func (m *AssetManifest) SelectAssets(assets []GithubAsset) ([]GithubAsset, error) {
+	selected := SliceFilter(assets, func(ass GithubAsset) bool { return m.Includes(ass.Name) })
+	if missing := m.MissingFiles(SliceMap(selected, func(ass GithubAsset) string { return ass.Name })); len(missing) != 0 {
+		return nil, errors.New("The release is missing " + strings.Join(missing, ", "))
+	}
+	return selected, nil
+}
+
+// IsStale reports whether name should be deleted from FilesDir if the installed build doesn't contain it
+func (m *AssetManifest) IsStale(name string) bool {
+	return SliceContainsFunc(m.Cleanup, func(pattern string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	})
+}
+
+// removeStaleFiles deletes files matching the cleanup patterns from FilesDir, except for keep.
+// Usually there are none, as dist is replaced as a whole, but swapping file by file leaves old files behind
+func removeStaleFiles(keep []string) {
+	entries, err := os.ReadDir(FilesDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || SliceContains(keep, name) || !VencordAssets.IsStale(name) {
+			continue
+		}
+
+		file := path.Join(FilesDir, name)
+		if planned(PlanRemove, file) {
+			continue
+		}
+		Log.Debug("Removing stale file", file)
+		if err = os.Remove(file); err != nil {
+			Log.Warn("Failed to remove stale file", file+":", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testAssets(names ...string) []GithubAsset {
+	return SliceMap(names, func(name string) GithubAsset { return GithubAsset{Name: name} })
+}
+
+func TestSelectAssets(t *testing.T) {
+	custom := &AssetManifest{Assets: []AssetRule{
+		{Name: "patcher.js", Required: true},
+		{Name: "vencordDesktopMain.js", Required: true},
+		{Name: "*.map"},
+	}}
+	full := []string{"patcher.js", "preload.js", "renderer.js", "renderer.css"}
+
+	tests := []struct {
+		name     string
+		manifest *AssetManifest
+		assets   []string
+		want     string
+		wantErr  string
+	}{
+		{"default", &DefaultAssetManifest, full, "patcher.js preload.js renderer.js renderer.css", ""},
+		{"optional patterns", &DefaultAssetManifest, append(full, "patcher.js.map", "renderer.js.LEGAL.txt", "LICENSE"),
+			"patcher.js preload.js renderer.js renderer.css patcher.js.map renderer.js.LEGAL.txt LICENSE", ""},
+		{"skips signatures and manifests", &DefaultAssetManifest,
+			append(full, "patcher.js.sig", AssetManifestName, ChecksumManifestNames[0], "installer-linux"),
+			"patcher.js preload.js renderer.js renderer.css", ""},
+		{"missing required", &DefaultAssetManifest, []string{"patcher.js", "renderer.js", "patcher.js.map"}, "", "missing preload.js, renderer.css"},
+		{"no assets", &DefaultAssetManifest, nil, "", "missing patcher.js, preload.js, renderer.js, renderer.css"},
+		{"custom manifest", custom, append(full, "vencordDesktopMain.js", "vencordDesktopMain.js.map"),
+			"patcher.js vencordDesktopMain.js vencordDesktopMain.js.map", ""},
+		{"custom manifest missing", custom, full, "", "missing vencordDesktopMain.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := tt.manifest.SelectAssets(testAssets(tt.assets...))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SelectAssets() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(SliceMap(selected, func(ass GithubAsset) string { return ass.Name }), " ")
+			if got != tt.want {
+				t.Errorf("SelectAssets() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAssetManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"valid", `{"assets": [{"name": "patcher.js", "required": true, "urls": ["https://example.com/{tag}/{name}"]}, {"name": "*.map"}], "cleanup": ["*.js"]}`, ""},
+		{"broken json", `{"assets": `, "unexpected end"},
+		{"no patcher.js", `{"assets": [{"name": "renderer.js", "required": true}]}`, "patcher.js must be a required asset"},
+		{"optional patcher.js", `{"assets": [{"name": "patcher.js"}]}`, "patcher.js must be a required asset"},
+		{"required pattern", `{"assets": [{"name": "patcher.js", "required": true}, {"name": "*.js", "required": true}]}`, "must not be a pattern"},
+		{"empty name", `{"assets": [{"name": "patcher.js", "required": true}, {"name": ""}]}`, "Invalid asset name"},
+		{"bad pattern", `{"assets": [{"name": "patcher.js", "required": true}, {"name": "[.js"}]}`, "Invalid asset name"},
+		{"path", `{"assets": [{"name": "patcher.js", "required": true}, {"name": "../evil.js"}]}`, "Invalid asset name"},
+		{"windows path", `{"assets": [{"name": "patcher.js", "required": true}, {"name": "..\\evil.js"}]}`, "Invalid asset name"},
+		{"parent dir", `{"assets": [{"name": "patcher.js", "required": true}, {"name": ".."}]}`, "Invalid asset name"},
+		{"bad cleanup pattern", `{"assets": [{"name": "patcher.js", "required": true}], "cleanup": ["[.js"]}`, "Invalid cleanup pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAssetManifest([]byte(tt.json))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ParseAssetManifest() = %v, want nil", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ParseAssetManifest() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
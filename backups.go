@@ -0,0 +1,412 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	path "path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Backups of the original app.asar are kept in BaseDir/backups/<install id>/<backup id>/
+
+const MaxBackupsPerInstall = 5
+
+type Backup struct {
+	Id           string    `json:"id"`
+	Install      string    `json:"install"` // DiscordInstall.path
+	Branch       string    `json:"branch"`
+	ResourcesDir string    `json:"resourcesDir"`
+	Source       string    `json:"source"` // the file that was backed up
+	Reason       string    `json:"reason"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Size         int64     `json:"size"`
+	Sha256       string    `json:"sha256"`
+	dir          string
+}
+
+func BackupsDir() string {
+	return path.Join(BaseDir, "backups")
+}
+
+func installBackupsDir(install string) string {
+	sum := sha256.Sum256([]byte(path.Clean(install)))
+	return path.Join(BackupsDir(), hex.EncodeToString(sum[:8]))
+}
+
+func (b *Backup) AsarFile() string {
+	return path.Join(b.dir, "app.asar")
+}
+
+// originalAsar returns Discord's own asar file of di, or "" if there is none
+func (di *DiscordInstall) originalAsar() string {
+	dir := di.resourcesDir()
+	if file := path.Join(dir, "_app.asar"); ExistsFile(file) {
+		return file
+	}
+	if di.patchState == PatchStateVencord || di.patchState == PatchStateStalePatcher {
+		// app.asar is our shim and the original is gone
+		return ""
+	}
+	return path.Join(dir, "app.asar")
+}
+
+// ErrNothingToBackUp is returned if di has no original asar, or it isn't worth keeping
+var ErrNothingToBackUp = errors.New("nothing to back up")
+
+// BackupOriginalAsar stores a snapshot of di's original asar, unless the newest backup already has the same content
+func BackupOriginalAsar(di *DiscordInstall, reason string) (*Backup, error) {
+	return backupOriginalAsar(di, reason, nil)
+}
+
+// backupOriginalAsar is BackupOriginalAsar, but never prunes the backup keep
+func backupOriginalAsar(di *DiscordInstall, reason string, keep *Backup) (*Backup, error) {
+	source := di.originalAsar()
+	if source == "" || !ExistsFile(source) {
+		return nil, fmt.Errorf("No original asar to back up: %w", ErrNothingToBackUp)
+	}
+
+	if err := validateAsar(source); err != nil {
+		return nil, fmt.Errorf("Not backing up %s: %w: %w", source, ErrNothingToBackUp, err)
+	}
+	if _, err := ReadShimMetadata(source); err == nil {
+		return nil, fmt.Errorf("Not backing up %s: %w: it is a Vencord shim, not Discord's original", source, ErrNothingToBackUp)
+	}
+
+	hash, size, err := hashFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	backups, err := ListBackups(di.path)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) != 0 && backups[0].Sha256 == hash {
+		Log.Debug("Newest backup", backups[0].Id, "is identical to", source+". Not backing up again")
+		return backups[0], nil
+	}
+
+	now := time.Now()
+	b := &Backup{
+		Id:           now.UTC().Format("20060102-150405") + "-" + hash[:8],
+		Install:      di.path,
+		Branch:       di.branch,
+		ResourcesDir: di.resourcesDir(),
+		Source:       source,
+		Reason:       reason,
+		CreatedAt:    now,
+		Size:         size,
+		Sha256:       hash,
+	}
+	b.dir = path.Join(installBackupsDir(di.path), b.Id)
+
+	if planned(PlanWrite, b.dir) {
+		return b, nil
+	}
+
+	Log.Info("Backing up", source, "to", b.dir)
+	if err = writeBackup(b); err != nil {
+		_ = os.RemoveAll(b.dir)
+		return nil, fmt.Errorf("Failed to back up %s: %w", source, err)
+	}
+	_ = FixOwnership(BackupsDir())
+
+	pruneBackups(append([]*Backup{b}, backups...), keep)
+	return b, nil
+}
+
+func writeBackup(b *Backup) error {
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return err
+	}
+
+	hash, _, err := copyFile(b.Source, b.AsarFile())
+	if err != nil {
+		return err
+	}
+	if hash != b.Sha256 {
+		return errors.New(b.Source + " changed while backing it up")
+	}
+
+	meta, err := json.MarshalIndent(b, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(b.dir, "meta.json"), meta, 0644)
+}
+
+// pruneBackups deletes all but the newest MaxBackupsPerInstall backups. backups must be sorted newest first.
+// keep is never deleted and may be nil
+func pruneBackups(backups []*Backup, keep *Backup) {
+	for i := MaxBackupsPerInstall; i < len(backups); i++ {
+		if keep != nil && backups[i].Id == keep.Id {
+			continue
+		}
+		Log.Debug("Deleting old backup", backups[i].Id)
+		if err := os.RemoveAll(backups[i].dir); err != nil {
+			Log.Warn("Failed to delete old backup", backups[i].dir+":", err)
+		}
+	}
+}
+
+// ListBackups returns all backups of the install at the given path, newest first
+func ListBackups(install string) ([]*Backup, error) {
+	return readBackups(installBackupsDir(install))
+}
+
+// ListAllBackups returns the backups of all installs, grouped by install and newest first
+func ListAllBackups() ([]*Backup, error) {
+	entries, err := os.ReadDir(BackupsDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var all []*Backup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		backups, err := readBackups(path.Join(BackupsDir(), entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, backups...)
+	}
+	return all, nil
+}
+
+func readBackups(dir string) ([]*Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var backups []*Backup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		backupDir := path.Join(dir, entry.Name())
+		meta, err := os.ReadFile(path.Join(backupDir, "meta.json"))
+		if err != nil {
+			Log.Warn("Ignoring backup without meta.json", backupDir)
+			continue
+		}
+
+		b := &Backup{dir: backupDir}
+		if err = json.Unmarshal(meta, b); err != nil {
+			Log.Warn("Ignoring backup with corrupt meta.json", backupDir+":", err)
+			continue
+		}
+		backups = append(backups, b)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// FindBackup looks up a backup by its id or a unique prefix of it
+func FindBackup(id string) (*Backup, error) {
+	backups, err := ListAllBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Backup
+	for _, b := range backups {
+		if b.Id == id {
+			return b, nil
+		}
+		if strings.HasPrefix(b.Id, id) {
+			if found != nil {
+				return nil, errors.New("Backup id " + id + " is ambiguous")
+			}
+			found = b
+		}
+	}
+	if found == nil {
+		return nil, errors.New("No backup with id " + id)
+	}
+	return found, nil
+}
+
+// RestoreBackup makes the backed up asar the app.asar of its install again, leaving it unpatched.
+// This works even if both app.asar and _app.asar are missing or corrupt. The asar it replaces is backed up first
+func RestoreBackup(b *Backup) error {
+	hash, _, err := hashFile(b.AsarFile())
+	if err != nil {
+		return fmt.Errorf("Failed to read backup: %w", err)
+	}
+	if hash != b.Sha256 {
+		return errors.New("Backup " + b.Id + " is corrupt: its hash does not match")
+	}
+
+	// Discord may have updated to a different app-x.y.z folder since the backup was taken
+	di := findInstall(b.Install)
+	if di == nil {
+		if !IsDirectory(b.ResourcesDir) {
+			return errors.New("Discord is no longer installed at " + b.ResourcesDir + ". Reinstall it, then restore the backup")
+		}
+		di = &DiscordInstall{
+			path:             b.Install,
+			branch:           b.Branch,
+			appPath:          path.Join(b.ResourcesDir, "app"),
+			isSystemElectron: path.Clean(b.ResourcesDir) == path.Clean(b.Install),
+		}
+		di.updatePatchState()
+	}
+	dir := di.resourcesDir()
+	PreparePatch(di)
+
+	// the current asar is deleted below, so keep it in case it was the one that worked
+	if _, err = backupOriginalAsar(di, "restore", b); errors.Is(err, ErrNothingToBackUp) {
+		Log.Debug(err)
+	} else if err != nil {
+		return fmt.Errorf("Failed to back up the current asar before restoring: %w", err)
+	}
+
+	appAsar := path.Join(dir, "app.asar")
+	_appAsar := path.Join(dir, "_app.asar")
+
+	var steps []JournalStep
+	var cleanup []string
+	for _, file := range []string{appAsar, _appAsar} {
+		if ExistsFile(file) {
+			steps = append(steps, RenameStep(file, file+".tmp"))
+			cleanup = append(cleanup, file+".tmp")
+		}
+	}
+	if ExistsFile(_appAsar+".unpacked") && !ExistsFile(appAsar+".unpacked") {
+		steps = append(steps, RenameStep(_appAsar+".unpacked", appAsar+".unpacked"))
+	}
+	steps = append(steps, CreateStep(appAsar))
+
+	journal, err := BeginJournal("restore", dir, steps, cleanup...)
+	if err != nil {
+		return err
+	}
+
+	err = journal.Run(func(file string) error {
+		Log.Debug("Copying", b.AsarFile(), "to", file)
+		_, _, err := copyFile(b.AsarFile(), file)
+		return err
+	})
+	if err != nil {
+		Log.Error("Failed to restore backup. Undoing partial restore")
+		if innerErr := journal.Rollback(); innerErr != nil {
+			Log.Error("Failed to undo partial restore. Try restoring again.", innerErr)
+		}
+		return err
+	}
+
+	journal.Commit()
+
+	di.setOpenAsar(nil)
+	di.setPatched(false)
+	return nil
+}
+
+func findInstall(p string) *DiscordInstall {
+	for _, d := range discords {
+		if di := d.(*DiscordInstall); di.path == p {
+			return di
+		}
+	}
+	return nil
+}
+
+// Label describes the backup in one line for selection lists
+func (b *Backup) Label() string {
+	//goland:noinspection GoDeprecation
+	return fmt.Sprintf("%s - %s - %s (%s)", b.Id, strings.Title(b.Branch), b.Install, b.Reason)
+}
+
+func WriteBackupList(w io.Writer, backups []*Backup) error {
+	if len(backups) == 0 {
+		_, err := fmt.Fprintln(w, "No backups found.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tCREATED\tSIZE\tREASON\tINSTALL")
+	for _, b := range backups {
+		//goland:noinspection GoDeprecation
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s - %s\n",
+			b.Id, b.CreatedAt.Local().Format("2006-01-02 15:04"), b.Size, b.Reason, strings.Title(b.Branch), b.Install)
+	}
+	return tw.Flush()
+}
+
+func validateAsar(file string) error {
+	a, err := OpenAsar(file)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if !a.Exists("package.json") {
+		return errors.New("asar has no package.json")
+	}
+	return nil
+}
+
+func hashFile(file string) (hash string, size int64, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if size, err = io.Copy(h, f); err != nil {
+		return
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// copyFile copies src to dst and returns the sha256 of the copied data
+func copyFile(src, dst string) (hash string, size int64, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return
+	}
+
+	h := sha256.New()
+	size, err = io.Copy(io.MultiWriter(out, h), in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
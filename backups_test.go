@@ -0,0 +1,121 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestAsar writes an asar that passes validateAsar, with version telling it apart from others
+func writeTestAsar(t *testing.T, file, version string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAsarPacker(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.AddFile("package.json", strings.NewReader(`{"version":"`+version+`"}`), AsarFileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestAsarVersion(t *testing.T, file string) string {
+	t.Helper()
+	a, err := OpenAsar(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := a.ReadFile("package.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(b), `{"version":"`), `"}`)
+}
+
+// setupBackupTest returns an install that isn't in discords, with MaxBackupsPerInstall backups of app.asar
+func setupBackupTest(t *testing.T) (*DiscordInstall, []*Backup) {
+	setupTestDirs(t)
+	prev := discords
+	discords = nil
+	t.Cleanup(func() { discords = prev })
+
+	install := t.TempDir()
+	resources := filepath.Join(install, "app-1.0.0", "resources")
+	di := &DiscordInstall{path: install, branch: "stable", appPath: filepath.Join(resources, "app")}
+	for i := 0; i < MaxBackupsPerInstall; i++ {
+		writeTestAsar(t, filepath.Join(resources, "app.asar"), "v"+string(rune('0'+i)))
+		if _, err := BackupOriginalAsar(di, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := ListBackups(install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return di, backups
+}
+
+func TestRestoreBackup(t *testing.T) {
+	di, backups := setupBackupTest(t)
+	oldest := backups[len(backups)-1]
+	appAsar := filepath.Join(di.resourcesDir(), "app.asar")
+	// Discord updated since the last backup
+	writeTestAsar(t, appAsar, "v5")
+
+	if err := RestoreBackup(oldest); err != nil {
+		t.Fatal(err)
+	}
+	if v := readTestAsarVersion(t, appAsar); v != "v0" {
+		t.Errorf("restored version %s, want v0", v)
+	}
+
+	after, err := ListBackups(di.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != MaxBackupsPerInstall+1 || after[0].Reason != "restore" || after[len(after)-1].Id != oldest.Id {
+		t.Fatalf("backups after restoring = %d, newest %s, oldest %s, want the replaced asar and the restored backup kept",
+			len(after), after[0].Reason, after[len(after)-1].Id)
+	}
+	if v := readTestAsarVersion(t, after[0].AsarFile()); v != "v5" {
+		t.Errorf("backed up version %s, want the replaced v5", v)
+	}
+	assertNoFile(t, appAsar+".tmp")
+}
+
+func TestRestoreBackupOfRemovedInstall(t *testing.T) {
+	setupTestDirs(t)
+	prev := discords
+	discords = nil
+	t.Cleanup(func() { discords = prev })
+
+	install := t.TempDir()
+	resources := filepath.Join(install, "app-1.0.0", "resources")
+	writeTestAsar(t, filepath.Join(resources, "app.asar"), "v0")
+	di := &DiscordInstall{path: install, branch: "stable", appPath: filepath.Join(resources, "app")}
+	b, err := BackupOriginalAsar(di, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.RemoveAll(filepath.Join(install, "app-1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = RestoreBackup(b); err == nil || !strings.Contains(err.Error(), "no longer installed") {
+		t.Errorf("RestoreBackup() = %v, want an error about the missing install", err)
+	}
+	assertNoFile(t, resources)
+}
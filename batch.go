@@ -0,0 +1,91 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+// SkipInstall is returned by batch actions that don't apply to an install, for example unpatching an unpatched install
+func SkipInstall(reason string) error {
+	return &skipError{reason}
+}
+
+func isSkipError(err error) bool {
+	var skip *skipError
+	return errors.As(err, &skip)
+}
+
+type InstallResult struct {
+	Install *DiscordInstall
+	Err     error
+}
+
+func (r *InstallResult) Skipped() bool {
+	return isSkipError(r.Err)
+}
+
+func (r *InstallResult) Failed() bool {
+	return r.Err != nil && !r.Skipped()
+}
+
+// RunForInstalls runs action on every install, continuing after failures
+func RunForInstalls(installs []*DiscordInstall, action string, fn func(di *DiscordInstall) error) []InstallResult {
+	results := make([]InstallResult, len(installs))
+	for i, di := range installs {
+		err := fn(di)
+		if err != nil {
+			if isSkipError(err) {
+				Log.Info("Skipping", di.path+":", err)
+			} else {
+				Log.Error("Failed to", action, di.path+":", err)
+			}
+		}
+		results[i] = InstallResult{di, err}
+	}
+	return results
+}
+
+func CountFailed(results []InstallResult) (failed int) {
+	for _, r := range results {
+		if r.Failed() {
+			failed++
+		}
+	}
+	return
+}
+
+// FormatInstallResults builds a per-install summary of a batch run
+func FormatInstallResults(results []InstallResult, action string) string {
+	var sb strings.Builder
+	for _, r := range results {
+		//goland:noinspection GoDeprecation
+		name := strings.Title(r.Install.branch) + " - " + r.Install.path
+		switch {
+		case r.Failed():
+			sb.WriteString(fmt.Sprintf("❌ %s: %s\n", name, r.Err))
+		case r.Skipped():
+			sb.WriteString(fmt.Sprintf("➖ %s: skipped, %s\n", name, r.Err))
+		default:
+			sb.WriteString(fmt.Sprintf("✔ %s: %s\n", name, action))
+		}
+	}
+
+	failed := CountFailed(results)
+	sb.WriteString(fmt.Sprintf("\n%d of %d installs failed", failed, len(results)))
+	return sb.String()
+}
@@ -0,0 +1,6 @@
+package buildinfo
+
+// Comma separated, base64 encoded Ed25519 public keys, set via -ldflags -X. Empty disables signature checks
+
+var VencordPublicKeys = ""
+var InstallerPublicKeys = ""
@@ -0,0 +1,297 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	path "path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"vencordinstaller/buildinfo"
+)
+
+// Installed builds are kept in BaseDir/builds/<version>/ so they can be rolled back to offline
+
+const MaxCachedBuilds = 3
+
+// BuildInfo describes a Vencord build and where it came from
+type BuildInfo struct {
+	Hash    string `json:"hash"`
+	Tag     string `json:"tag,omitempty"`     // empty for local builds
+	Channel string `json:"channel,omitempty"` // empty for local builds
+	Source  string `json:"source"`            // url of the release or path of the local builds
+}
+
+type CachedBuild struct {
+	BuildInfo
+	CreatedAt time.Time         `json:"createdAt"`
+	Files     map[string]string `json:"files"` // file name -> sha256
+	dir       string
+}
+
+func BuildsDir() string {
+	return path.Join(BaseDir, "builds")
+}
+
+func buildDir(hash string) string {
+	return path.Join(BuildsDir(), SanitizeFileName(hash))
+}
+
+// IsActive reports whether this build is the one currently in FilesDir
+func (b *CachedBuild) IsActive() bool {
+	return b.Hash == InstalledHash
+}
+
+// installBuild moves the complete build in staging into the cache and makes it the active one
+func installBuild(staging string, info BuildInfo) error {
+	dir := buildDir(info.Hash)
+	if IsDryRun() {
+		planBuild(info.Hash)
+		planned(PlanRename, staging, dir)
+		files := append([]string{"package.json"}, VencordAssets.RequiredFiles()...)
+		for _, name := range files {
+			planned(PlanWrite, path.Join(FilesDir, name))
+		}
+		removeStaleFiles(files)
+		planned(PlanWrite, ManifestFile())
+		return nil
+	}
+
+	b, err := cacheBuild(staging, dir, info)
+	if err != nil {
+		return fmt.Errorf("Failed to cache build %s: %w", info.Hash, err)
+	}
+	if err = ActivateBuild(b); err != nil {
+		return err
+	}
+
+	pruneBuilds()
+	return nil
+}
+
+func cacheBuild(staging, dir string, info BuildInfo) (*CachedBuild, error) {
+	b := &CachedBuild{
+		BuildInfo: info,
+		CreatedAt: time.Now().UTC(),
+		Files:     make(map[string]string),
+		dir:       dir,
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		hash, _, err := hashFile(path.Join(staging, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		b.Files[entry.Name()] = hash
+	}
+
+	// installing the same version again replaces it
+	if err = os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(BuildsDir(), 0755); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(staging, dir); err != nil {
+		return nil, err
+	}
+
+	meta, err := json.MarshalIndent(b, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(path.Join(dir, "build.json"), meta, 0644); err != nil {
+		return nil, err
+	}
+
+	_ = FixOwnership(BuildsDir())
+	return b, nil
+}
+
+// ActivateBuild copies the cached build into FilesDir and writes the install manifest. The copy goes through
+// a staging dir and is checked against the hashes in build.json, so a corrupt cache never replaces a working dist
+func ActivateBuild(b *CachedBuild) error {
+	if IsDryRun() {
+		planBuild(b.Hash)
+		for _, name := range SortedKeys(b.Files) {
+			planned(PlanWrite, path.Join(FilesDir, name))
+		}
+		removeStaleFiles(SortedKeys(b.Files))
+		planned(PlanWrite, ManifestFile())
+		return nil
+	}
+
+	staging := StagingDir("activate-" + b.Hash)
+	_ = os.RemoveAll(staging)
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return fmt.Errorf("Failed to create staging dir: %w", err)
+	}
+
+	for _, name := range SortedKeys(b.Files) {
+		hash, _, err := copyFile(path.Join(b.dir, name), path.Join(staging, name))
+		if err == nil && hash != b.Files[name] {
+			err = errors.New("Cached build " + b.Hash + " is corrupt: the hash of " + name + " does not match")
+		}
+		if err != nil {
+			_ = os.RemoveAll(staging)
+			return err
+		}
+	}
+
+	if err := swapIntoDist(staging); err != nil {
+		_ = os.RemoveAll(staging)
+		return fmt.Errorf("Failed to move build %s to %s: %w", b.Hash, FilesDir, err)
+	}
+
+	removeStaleFiles(SortedKeys(b.Files))
+	Log.Debug("Activated build", b.Hash)
+	_ = FixOwnership(FilesDir)
+
+	m := &InstallManifest{
+		BuildInfo:        b.BuildInfo,
+		Files:            b.Files,
+		InstalledAt:      time.Now().UTC(),
+		InstallerVersion: buildinfo.InstallerTag + " (" + buildinfo.InstallerGitHash + ")",
+	}
+	if err := writeInstallManifest(m); err != nil {
+		// dist was already replaced, so this is not worth failing the install over
+		Log.Warn("Failed to write the install manifest:", err)
+	}
+	stateLock.Lock()
+	InstalledManifest = m
+	InstalledHash = b.Hash
+	stateLock.Unlock()
+	return nil
+}
+
+// pruneBuilds deletes all but the newest MaxCachedBuilds builds. The active build is always kept
+func pruneBuilds() {
+	builds, err := ListBuilds()
+	if err != nil {
+		Log.Warn("Failed to list cached builds:", err)
+		return
+	}
+
+	kept := 0
+	for _, b := range builds {
+		if kept < MaxCachedBuilds || b.IsActive() {
+			kept++
+			continue
+		}
+		Log.Debug("Deleting old build", b.Hash)
+		if err = os.RemoveAll(b.dir); err != nil {
+			Log.Warn("Failed to delete old build", b.dir+":", err)
+		}
+	}
+}
+
+// ListBuilds returns all cached builds, newest first
+func ListBuilds() ([]*CachedBuild, error) {
+	entries, err := os.ReadDir(BuildsDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var builds []*CachedBuild
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := path.Join(BuildsDir(), entry.Name())
+		meta, err := os.ReadFile(path.Join(dir, "build.json"))
+		if err != nil {
+			Log.Warn("Ignoring cached build without build.json", dir)
+			continue
+		}
+
+		b := &CachedBuild{dir: dir}
+		if err = json.Unmarshal(meta, b); err != nil {
+			Log.Warn("Ignoring cached build with corrupt build.json", dir+":", err)
+			continue
+		}
+		if b.Hash == "" {
+			// cached before build.json had a hash. The directory is named after it
+			b.Hash = entry.Name()
+		}
+		builds = append(builds, b)
+	}
+
+	sort.Slice(builds, func(i, j int) bool {
+		return builds[i].CreatedAt.After(builds[j].CreatedAt)
+	})
+	return builds, nil
+}
+
+// FindBuild looks up a cached build by its hash or a unique prefix of it
+func FindBuild(hash string) (*CachedBuild, error) {
+	builds, err := ListBuilds()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *CachedBuild
+	for _, b := range builds {
+		if b.Hash == hash {
+			return b, nil
+		}
+		if strings.HasPrefix(b.Hash, hash) {
+			if found != nil {
+				return nil, errors.New("Version " + hash + " is ambiguous")
+			}
+			found = b
+		}
+	}
+	if found == nil {
+		return nil, errors.New("No cached build with version " + hash)
+	}
+	return found, nil
+}
+
+// Label describes the build in one line for selection lists
+func (b *CachedBuild) Label() string {
+	return fmt.Sprintf("%s - %s (%s)%s", b.Hash, b.CreatedAt.Local().Format("2006-01-02 15:04"), b.describeSource(), Ternary(b.IsActive(), " - active", ""))
+}
+
+func WriteBuildList(w io.Writer, builds []*CachedBuild) error {
+	if len(builds) == 0 {
+		_, err := fmt.Fprintln(w, "No cached builds found.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tCACHED\tACTIVE\tSOURCE")
+	for _, b := range builds {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			b.Hash, b.CreatedAt.Local().Format("2006-01-02 15:04"), Ternary(b.IsActive(), "yes", ""), b.describeSource())
+	}
+	return tw.Flush()
+}
+
+// describeSource returns the tag and channel of remote builds and the path of local ones
+func (b *BuildInfo) describeSource() string {
+	if b.Tag == "" {
+		return b.Source
+	}
+	return b.Tag + ", " + b.Channel + " channel"
+}
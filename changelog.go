@@ -0,0 +1,123 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Changelogs come from the GitHub compare api, or from Settings.CompareUrls
+
+type ChangelogCommit struct {
+	Hash    string
+	Message string // first line only
+	Author  string
+	Date    time.Time
+}
+
+type Changelog struct {
+	Base    string
+	Head    string
+	Commits []ChangelogCommit // newest first
+	Total   int               // can be more than len(Commits), the compare api returns at most 250
+}
+
+type githubCompare struct {
+	TotalCommits int `json:"total_commits"`
+	Commits      []struct {
+		Sha    string `json:"sha"`
+		Commit struct {
+			Message string `json:"message"`
+			Author  struct {
+				Name string    `json:"name"`
+				Date time.Time `json:"date"`
+			} `json:"author"`
+		} `json:"commit"`
+	} `json:"commits"`
+}
+
+// CanShowChangelog reports whether an update is available that a changelog can be fetched for
+func CanShowChangelog() bool {
+	return !IsDevInstall && LocalBuildsSource == "" && GithubError == nil &&
+		InstalledHash != LatestHash && isCommitHash(InstalledHash) && isCommitHash(LatestHash)
+}
+
+func isCommitHash(s string) bool {
+	if len(s) < 7 || len(s) > 40 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// FetchChangelog fetches the commits between the builds base and head
+func FetchChangelog(ctx context.Context, base, head string) (*Changelog, error) {
+	if base == head {
+		return nil, errors.New("Vencord is up to date")
+	}
+	if !isCommitHash(base) || !isCommitHash(head) {
+		return nil, fmt.Errorf("No changelog available between %s and %s", base, head)
+	}
+
+	urls := SliceMap(Settings.CompareUrls, func(url string) string {
+		return strings.NewReplacer("{base}", base, "{head}", head).Replace(url)
+	})
+	b, err := downloadBytesFromMirrors(ctx, urls)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch changelog: %w", err)
+	}
+
+	var data githubCompare
+	if err = json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("Failed to decode changelog: %w", err)
+	}
+
+	c := &Changelog{Base: base, Head: head, Total: data.TotalCommits}
+	for i := len(data.Commits) - 1; i >= 0; i-- {
+		commit := data.Commits[i]
+		message, _, _ := strings.Cut(commit.Commit.Message, "\n")
+		c.Commits = append(c.Commits, ChangelogCommit{
+			Hash:    commit.Sha,
+			Message: strings.TrimSpace(message),
+			Author:  commit.Commit.Author.Name,
+			Date:    commit.Commit.Author.Date,
+		})
+	}
+	if c.Total < len(c.Commits) {
+		c.Total = len(c.Commits)
+	}
+	return c, nil
+}
+
+func (c *Changelog) String() string {
+	if len(c.Commits) == 0 {
+		return fmt.Sprintf("No new commits between %s and %s", c.Base, c.Head)
+	}
+
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "%d new commit%s between %s and %s:\n", c.Total, Ternary(c.Total == 1, "", "s"), c.Base, c.Head)
+	for _, commit := range c.Commits {
+		hash := commit.Hash
+		if len(hash) > 7 {
+			hash = hash[:7]
+		}
+		_, _ = fmt.Fprintf(&sb, "  %s  %s (%s, %s)\n", hash, commit.Message, commit.Author, commit.Date.Local().Format("2006-01-02"))
+	}
+	if more := c.Total - len(c.Commits); more > 0 {
+		_, _ = fmt.Fprintf(&sb, "  ...and %d more\n", more)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
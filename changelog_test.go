@@ -0,0 +1,122 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCompare = `{
+	"total_commits": %d,
+	"commits": [
+		{"sha": "1111111aaaa", "commit": {"message": "oldest commit\n\nwith a body", "author": {"name": "Ven", "date": "2023-05-01T12:00:00Z"}}},
+		{"sha": "2222222bbbb", "commit": {"message": "  newest commit  ", "author": {"name": "Nuckyz", "date": "2023-05-02T12:00:00Z"}}}
+	]
+}`
+
+func TestFetchChangelog(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		switch r.URL.Query().Get("case") {
+		case "truncated":
+			_, _ = w.Write([]byte(strings.Replace(testCompare, "%d", "300", 1)))
+		case "understated":
+			_, _ = w.Write([]byte(strings.Replace(testCompare, "%d", "0", 1)))
+		case "empty":
+			_, _ = w.Write([]byte(`{"total_commits": 0, "commits": []}`))
+		case "broken":
+			_, _ = w.Write([]byte(`{"commits": `))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	prev := Settings.CompareUrls
+	t.Cleanup(func() { Settings.CompareUrls = prev })
+
+	tests := []struct {
+		name       string
+		base, head string
+		query      string
+		wantErr    string
+		wantTotal  int
+		wantString string
+	}{
+		{"up to date", "abcdef1", "abcdef1", "truncated", "up to date", 0, ""},
+		{"not a commit", "Unknown", "abcdef1", "truncated", "No changelog available", 0, ""},
+		{"not found", "abcdef1", "1234567", "missing", "Failed to fetch changelog", 0, ""},
+		{"broken json", "abcdef1", "1234567", "broken", "Failed to decode changelog", 0, ""},
+		{"more than returned", "abcdef1", "1234567", "truncated", "", 300,
+			"300 new commits between abcdef1 and 1234567:\n" +
+				"  2222222  newest commit (Nuckyz, 2023-05-02)\n" +
+				"  1111111  oldest commit (Ven, 2023-05-01)\n" +
+				"  ...and 298 more"},
+		{"total below returned", "abcdef1", "1234567", "understated", "", 2, ""},
+		{"no commits", "abcdef1", "1234567", "empty", "", 0, "No new commits between abcdef1 and 1234567"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Settings.CompareUrls = []string{srv.URL + "/compare/{base}...{head}?case=" + tt.query}
+
+			c, err := FetchChangelog(context.Background(), tt.base, tt.head)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FetchChangelog() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotPath != "/compare/"+tt.base+"..."+tt.head {
+				t.Errorf("requested %s", gotPath)
+			}
+			if c.Total != tt.wantTotal {
+				t.Errorf("Total = %d, want %d", c.Total, tt.wantTotal)
+			}
+			if tt.wantString != "" && c.String() != tt.wantString {
+				t.Errorf("String() =\n%s\nwant\n%s", c.String(), tt.wantString)
+			}
+		})
+	}
+}
+
+func TestChangelogStringOneCommit(t *testing.T) {
+	c := &Changelog{Base: "a", Head: "b", Total: 1, Commits: []ChangelogCommit{
+		{Hash: "abc", Message: "short hash", Author: "Ven", Date: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)},
+	}}
+	want := "1 new commit between a and b:\n  abc  short hash (Ven, 2023-05-01)"
+	if c.String() != want {
+		t.Errorf("String() =\n%s\nwant\n%s", c.String(), want)
+	}
+}
+
+func TestIsCommitHash(t *testing.T) {
+	tests := map[string]bool{
+		"abcdef1":    true,
+		"ABCDEF1234": true,
+		"1234567890123456789012345678901234567890": true,
+		"abcdef":             false,
+		"Unknown":            false,
+		"None":               false,
+		"abcdefg1":           false,
+		"local-1234567890ab": false,
+		"12345678901234567890123456789012345678901": false,
+	}
+	for s, want := range tests {
+		if got := isCommitHash(s); got != want {
+			t.Errorf("isCommitHash(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Release channels. tag:<name> pins the release with that tag
+
+const (
+	ChannelStable     = "stable"
+	ChannelPrerelease = "prerelease"
+	ChannelDevbuild   = "devbuild"
+	ChannelTagPrefix  = "tag:"
+)
+
+// CurrentChannel returns the channel to fetch releases from
+func CurrentChannel() string {
+	if Settings.Channel == "" {
+		return ChannelStable
+	}
+	return Settings.Channel
+}
+
+// ParseChannel validates channel and returns it in its canonical form
+func ParseChannel(channel string) (string, error) {
+	channel = strings.TrimSpace(channel)
+	switch channel {
+	case ChannelStable, ChannelPrerelease, ChannelDevbuild:
+		return channel, nil
+	case "", "latest":
+		return ChannelStable, nil
+	}
+
+	if tag, ok := strings.CutPrefix(channel, ChannelTagPrefix); ok {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			return "", errors.New("Missing tag name. Use " + ChannelTagPrefix + "<name>")
+		}
+		return ChannelTagPrefix + tag, nil
+	}
+	return "", fmt.Errorf("Invalid channel %s. Must be one of %s, %s, %s or %s<name>", channel, ChannelStable, ChannelPrerelease, ChannelDevbuild, ChannelTagPrefix)
+}
+
+// SaveChannel makes channel the current channel and saves it in the config file
+func SaveChannel(channel string) error {
+	Settings.Channel = channel
+	return saveConfigValue("channel", channel)
+}
+
+// channelTag returns the tag that channel is pinned to, if any
+func channelTag(channel string) (string, bool) {
+	if channel == ChannelDevbuild {
+		return "devbuild", true
+	}
+	return strings.CutPrefix(channel, ChannelTagPrefix)
+}
+
+// channelReleaseUrl turns a url of the latest release into the one of channel. Only GitHub api urls
+// can be turned into other channels, for everything else ok is false
+func channelReleaseUrl(releaseUrl, channel string) (_ string, ok bool) {
+	if channel == ChannelStable {
+		return releaseUrl, true
+	}
+
+	base, ok := strings.CutSuffix(releaseUrl, "/releases/latest")
+	if !ok {
+		return "", false
+	}
+	if channel == ChannelPrerelease {
+		// sorted newest first. fetchGithubRelease picks the first one that isn't a draft
+		return base + "/releases?per_page=10", true
+	}
+	tag, _ := channelTag(channel)
+	return base + "/releases/tags/" + url.PathEscape(tag), true
+}
+
+// ChannelReleaseUrls returns the urls to fetch the release of channel from. Urls that can't be turned into
+// channel, like our fallback, are kept as they are. Use CheckChannelRelease on what they return
+func ChannelReleaseUrls(urls []string, channel string) ([]string, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoUrls
+	}
+	return SliceMap(urls, func(u string) string {
+		if channelUrl, ok := channelReleaseUrl(u, channel); ok {
+			return channelUrl
+		}
+		Log.Debug("Using", u, "as is as it doesn't support the", channel, "channel")
+		return u
+	}), nil
+}
+
+// CheckChannelRelease returns an error if release isn't the one pinned by channel
+func CheckChannelRelease(release *GithubRelease, channel string) error {
+	if tag, ok := channelTag(channel); ok && release.TagName != tag {
+		return fmt.Errorf("%s has release %s instead of the %s one", release.source, release.TagName, tag)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChannelReleaseUrls(t *testing.T) {
+	urls := []string{ReleaseUrl, ReleaseUrlFallback}
+	base := "https://api.github.com/repos/Vendicated/Vencord/releases"
+
+	tests := []struct {
+		channel string
+		want    []string
+	}{
+		{ChannelStable, []string{ReleaseUrl, ReleaseUrlFallback}},
+		{ChannelPrerelease, []string{base + "?per_page=10", ReleaseUrlFallback}},
+		{ChannelDevbuild, []string{base + "/tags/devbuild", ReleaseUrlFallback}},
+		{"tag:v1.0 beta", []string{base + "/tags/v1.0%20beta", ReleaseUrlFallback}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			got, err := ChannelReleaseUrls(urls, tt.channel)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChannelReleaseUrls() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ChannelReleaseUrls(nil, ChannelStable); err == nil {
+		t.Error("ChannelReleaseUrls(nil) succeeded")
+	}
+}
+
+func TestCheckChannelRelease(t *testing.T) {
+	tests := []struct {
+		channel string
+		tag     string
+		wantErr bool
+	}{
+		{ChannelStable, "v1.0", false},
+		{ChannelPrerelease, "v1.1-beta", false},
+		{ChannelDevbuild, "devbuild", false},
+		{ChannelDevbuild, "v1.0", true},
+		{"tag:v1.0", "v1.0", false},
+		{"tag:v1.0", "v1.1", true},
+	}
+	for _, tt := range tests {
+		err := CheckChannelRelease(&GithubRelease{TagName: tt.tag, source: ReleaseUrlFallback}, tt.channel)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckChannelRelease(%s, %s) = %v, want error %v", tt.tag, tt.channel, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Release assets whose content is a sha256sum style manifest of the other assets
+var ChecksumManifestNames = []string{"SHA256SUMS", "SHA256SUMS.txt", "sha256sums.txt", "checksums.txt"}
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+var ErrUnverified = errors.New("no checksum or signature to verify it against")
+
+// Checksums maps asset names to their lowercase hex sha256
+type Checksums map[string]string
+
+// FetchChecksums collects the sha256 of all assets of release, from GitHub's asset digests
+// and, if the release has one, its checksum manifest. The digests win if both exist, as GitHub computes them itself
+func FetchChecksums(ctx context.Context, release *GithubRelease) (Checksums, error) {
+	checksums := make(Checksums)
+	for _, ass := range release.Assets {
+		if hash, ok := strings.CutPrefix(ass.Digest, "sha256:"); ok {
+			checksums[ass.Name] = strings.ToLower(hash)
+		}
+	}
+
+	for _, ass := range release.Assets {
+		if !SliceContains(ChecksumManifestNames, ass.Name) {
+			continue
+		}
+
+		Log.Debug("Fetching checksum manifest", ass.Name)
+		b, err := downloadBytesFromMirrors(ctx, assetUrls(release, ass))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to fetch checksum manifest %s: %w", ass.Name, err)
+		}
+		if hash, ok := checksums[ass.Name]; ok {
+			if err = verifySha256(ass.Name, b, hash); err != nil {
+				return nil, err
+			}
+		}
+
+		for name, hash := range parseChecksumManifest(b) {
+			if _, ok := checksums[name]; !ok {
+				checksums[name] = hash
+			}
+		}
+		break
+	}
+
+	return checksums, nil
+}
+
+// parseChecksumManifest parses the output of sha256sum: "<hash>  <name>" or "<hash> *<name>" per line
+func parseChecksumManifest(b []byte) Checksums {
+	checksums := make(Checksums)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		hash, name, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok || len(hash) != sha256.Size*2 {
+			continue
+		}
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		// some tools write paths like ./dist/patcher.js
+		if i := strings.LastIndex(name, "/"); i != -1 {
+			name = name[i+1:]
+		}
+		checksums[name] = strings.ToLower(hash)
+	}
+	return checksums
+}
+
+// Verify checks data against the checksum of the asset name. Returns false if there is no checksum for it
+func (c Checksums) Verify(name string, data []byte) (bool, error) {
+	hash, ok := c[name]
+	if !ok {
+		return false, nil
+	}
+	return true, verifySha256(name, data, hash)
+}
+
+// checkVerified refuses assets that neither a checksum nor a signature vouched for, unless that was allowed
+func checkVerified(name string, verified bool) error {
+	if verified {
+		return nil
+	}
+	if !Settings.AllowUnverified {
+		return fmt.Errorf("%s: %w. Set VENCORD_ALLOW_UNVERIFIED=1 or pass --allow-unverified to install it anyway", name, ErrUnverified)
+	}
+	Log.Warn("No checksum available for", name+". Installing it unverified")
+	return nil
+}
+
+func verifySha256(name string, data []byte, expected string) error {
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("%s: %w. Expected sha256 %s, got %s", name, ErrChecksumMismatch, expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseChecksumManifest(t *testing.T) {
+	patcher, renderer, preload := sha256Hex("patcher"), sha256Hex("renderer"), sha256Hex("preload")
+	manifest := strings.Join([]string{
+		patcher + "  patcher.js",
+		strings.ToUpper(renderer) + " *renderer.js",
+		preload + "  ./dist/preload.js",
+		"",
+		"not a checksum line",
+		"abc123  short.js",
+	}, "\n")
+
+	want := Checksums{"patcher.js": patcher, "renderer.js": renderer, "preload.js": preload}
+	if got := parseChecksumManifest([]byte(manifest)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChecksumManifest() = %v, want %v", got, want)
+	}
+}
+
+func TestChecksumsVerify(t *testing.T) {
+	checksums := Checksums{"patcher.js": sha256Hex("patcher")}
+
+	tests := []struct {
+		name         string
+		file         string
+		data         string
+		wantVerified bool
+		wantErr      error
+	}{
+		{"match", "patcher.js", "patcher", true, nil},
+		{"mismatch", "patcher.js", "tampered", true, ErrChecksumMismatch},
+		{"missing entry", "renderer.js", "renderer", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verified, err := checksums.Verify(tt.file, []byte(tt.data))
+			if verified != tt.wantVerified || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, %v, want %v, %v", verified, err, tt.wantVerified, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckVerified(t *testing.T) {
+	allow := Settings.AllowUnverified
+	t.Cleanup(func() { Settings.AllowUnverified = allow })
+
+	Settings.AllowUnverified = false
+	if err := checkVerified("patcher.js", true); err != nil {
+		t.Errorf("checkVerified(verified) = %v", err)
+	}
+	if err := checkVerified("patcher.js", false); !errors.Is(err, ErrUnverified) {
+		t.Errorf("checkVerified(unverified) = %v, want ErrUnverified", err)
+	}
+
+	Settings.AllowUnverified = true
+	if err := checkVerified("patcher.js", false); err != nil {
+		t.Errorf("checkVerified(unverified) with AllowUnverified = %v", err)
+	}
+}
+
+func TestFetchChecksums(t *testing.T) {
+	manifest := sha256Hex("from manifest") + "  patcher.js\n" + sha256Hex("renderer") + "  renderer.js\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(manifest))
+	}))
+	defer srv.Close()
+
+	settings := Settings
+	t.Cleanup(func() { Settings = settings })
+	Settings.AssetMirrors, Settings.AssetFallbackUrls = nil, nil
+
+	tests := []struct {
+		name    string
+		assets  []GithubAsset
+		want    Checksums
+		wantErr error
+	}{
+		{
+			name: "digests only",
+			assets: []GithubAsset{
+				{Name: "patcher.js", Digest: "sha256:" + strings.ToUpper(sha256Hex("patcher"))},
+				{Name: "renderer.js"},
+			},
+			want: Checksums{"patcher.js": sha256Hex("patcher")},
+		},
+		{
+			name: "manifest fills in missing digests",
+			assets: []GithubAsset{
+				{Name: "patcher.js"},
+				{Name: "renderer.js"},
+				{Name: "SHA256SUMS", DownloadURL: srv.URL + "/SHA256SUMS"},
+			},
+			want: Checksums{"patcher.js": sha256Hex("from manifest"), "renderer.js": sha256Hex("renderer")},
+		},
+		{
+			name: "digest takes priority over the manifest",
+			assets: []GithubAsset{
+				{Name: "patcher.js", Digest: "sha256:" + sha256Hex("patcher")},
+				{Name: "renderer.js"},
+				{Name: "SHA256SUMS", DownloadURL: srv.URL + "/SHA256SUMS"},
+			},
+			want: Checksums{"patcher.js": sha256Hex("patcher"), "renderer.js": sha256Hex("renderer")},
+		},
+		{
+			name: "manifest not matching its own digest",
+			assets: []GithubAsset{
+				{Name: "SHA256SUMS", DownloadURL: srv.URL + "/SHA256SUMS", Digest: "sha256:" + sha256Hex("other")},
+			},
+			wantErr: ErrChecksumMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FetchChecksums(context.Background(), &GithubRelease{TagName: "v1", Assets: tt.assets})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FetchChecksums() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			// the manifest verifies itself if it has a digest, so ignore that entry
+			delete(got, "SHA256SUMS")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FetchChecksums() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
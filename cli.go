@@ -9,12 +9,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
 	"strings"
+	"time"
 	"vencordinstaller/buildinfo"
 
 	"github.com/fatih/color"
@@ -39,9 +42,6 @@ func die(msg string) {
 }
 
 func main() {
-	InitGithubDownloader()
-	discords = FindDiscords()
-
 	// Used by log.go init func
 	flag.Bool("debug", false, "Enable debug info")
 
@@ -53,10 +53,84 @@ func main() {
 	var uninstallFlag = flag.Bool("uninstall", false, "Uninstall Vencord")
 	var installOpenAsarFlag = flag.Bool("install-openasar", false, "Install OpenAsar")
 	var uninstallOpenAsarFlag = flag.Bool("uninstall-openasar", false, "Uninstall OpenAsar")
+	var doctorFlag = flag.Bool("doctor", false, "Diagnose all Discord installs")
+	var inspectAsarFlag = flag.Bool("inspect-asar", false, "Print the contents of an asar file. Pass its path as argument or select an install")
+	var listBackupsFlag = flag.Bool("list-backups", false, "List the backups of Discord's original app.asar")
+	var restoreBackupFlag = flag.String("restore-backup", "", "Restore the backup with the given id (see --list-backups)")
+	var listBuildsFlag = flag.Bool("list-builds", false, "List the cached Vencord builds")
+	var changelogFlag = flag.Bool("changelog", false, "Show the changes between the installed and the latest Vencord version")
+	var rollbackFlag = flag.String("rollback", "", "Switch back to the cached Vencord build with the given version (see --list-builds). Works offline")
 	var locationFlag = flag.String("location", "", "The location of the Discord install to modify")
 	var branchFlag = flag.String("branch", "", "The branch of Discord to modify [auto|stable|ptb|canary]")
+	var allFlag = flag.Bool("all", false, "Modify all detected Discord installs")
+	var dryRunFlag = flag.Bool("dry-run", false, "Only print the operations that would be performed, without changing anything")
+	var discardJournalsFlag = flag.Bool("discard-journals", false, "Forget interrupted operations that can't be rolled back. Only use this after fixing the files listed in their journals")
+	var allowUnverifiedFlag = flag.Bool("allow-unverified", false, "Install Vencord files even if there is no checksum or signature to verify them against")
+	var offlineFlag = flag.String("offline", "", "Install Vencord from a local folder, .zip or .tar.gz containing its dist files instead of downloading it")
+	var releaseUrlsFlag = flag.String("release-urls", "", "Comma separated list of urls to fetch the Vencord release from, tried in order")
+	var assetMirrorsFlag = flag.String("asset-mirrors", "", "Comma separated list of mirrors to download Vencord's files from before trying GitHub")
+	var assetFallbackUrlsFlag = flag.String("asset-fallback-urls", "", "Comma separated list of urls to download Vencord's files from if GitHub fails, tried in order. {tag} and {name} are replaced with the release tag and file name")
+	var installerReleaseUrlsFlag = flag.String("installer-release-urls", "", "Comma separated list of urls to fetch the installer release from, tried in order")
+	var openAsarUrlsFlag = flag.String("openasar-urls", "", "Comma separated list of urls to download OpenAsar from, tried in order")
+	var compareUrlsFlag = flag.String("compare-urls", "", "Comma separated list of urls to fetch the changelog from, tried in order. {base} and {head} are replaced with the versions")
+	var channelFlag = flag.String("channel", "", "The release channel to follow [stable|prerelease|devbuild|tag:<name>]. Remembered for later runs")
 	flag.Parse()
 
+	// a dry run must not touch anything, not even to recover
+	if *dryRunFlag {
+		StartDryRun()
+		if err := ReportJournals(); err != nil {
+			Log.Error(err)
+		}
+	} else if *discardJournalsFlag {
+		if err := DiscardJournals(); err != nil {
+			die(err.Error())
+		}
+		exitSuccess()
+	} else if err := RecoverJournals(); err != nil {
+		Log.Error(err)
+		Log.Warn("Run me again to retry rolling back, or fix the files listed in the journal and run with --discard-journals")
+	}
+	discords = FindDiscords()
+
+	if err := LoadConfig(); err != nil {
+		die(err.Error())
+	}
+	urlsErr := errors.Join(
+		OverrideUrls("--release-urls", &Settings.ReleaseUrls, *releaseUrlsFlag),
+		OverrideUrls("--asset-mirrors", &Settings.AssetMirrors, *assetMirrorsFlag),
+		OverrideUrls("--asset-fallback-urls", &Settings.AssetFallbackUrls, *assetFallbackUrlsFlag),
+		OverrideUrls("--installer-release-urls", &Settings.InstallerReleaseUrls, *installerReleaseUrlsFlag),
+		OverrideUrls("--openasar-urls", &Settings.OpenAsarUrls, *openAsarUrlsFlag),
+		OverrideUrls("--compare-urls", &Settings.CompareUrls, *compareUrlsFlag),
+	)
+	if urlsErr != nil {
+		die(urlsErr.Error())
+	}
+	if *allowUnverifiedFlag {
+		Settings.AllowUnverified = true
+	}
+
+	if *channelFlag != "" {
+		channel, err := ParseChannel(*channelFlag)
+		if err != nil {
+			die(err.Error())
+		}
+		if *dryRunFlag {
+			Settings.Channel = channel
+		} else if err = SaveChannel(channel); err != nil {
+			Log.Warn("Failed to remember the channel, it will only be used this time:", err)
+		}
+	}
+
+	if *offlineFlag != "" {
+		if err := SetLocalBuildsSource(*offlineFlag); err != nil {
+			die(err.Error())
+		}
+	}
+	InitGithubDownloader()
+	InitSelfUpdater()
+
 	if *helpFlag {
 		flag.Usage()
 		return
@@ -66,6 +140,7 @@ func main() {
 		fmt.Println("Vencord Installer Cli", buildinfo.InstallerTag, "("+buildinfo.InstallerGitHash+")")
 		fmt.Println("Copyright (C) 2023 Vendicated and Vencord contributors")
 		fmt.Println("License GPLv3+: GNU GPL version 3 or later <https://gnu.org/licenses/gpl.html>.")
+		fmt.Println("Signature verification: Vencord " + VencordSigningKeys.String() + ", installer " + InstallerSigningKeys.String())
 		return
 	}
 
@@ -84,6 +159,10 @@ func main() {
 		die("The 'location' and 'branch' flags are mutually exclusive.")
 	}
 
+	if *allFlag && (*locationFlag != "" || *branchFlag != "") {
+		die("The 'all' flag can't be combined with 'location' or 'branch'.")
+	}
+
 	if !isValidBranch(*branchFlag) {
 		die("The 'branch' flag must be one of the following: [auto|stable|ptb|canary]")
 	}
@@ -92,10 +171,16 @@ func main() {
 		if !<-GithubDoneChan {
 			die("Not " + Ternary(*installFlag, "installing", "updating") + " as fetching release data failed")
 		}
+	} else if *changelogFlag {
+		if !<-GithubDoneChan {
+			die("Can't show the changelog as fetching release data failed")
+		}
 	}
 
-	install, uninstall, update, installOpenAsar, uninstallOpenAsar := *installFlag, *uninstallFlag, *updateFlag, *installOpenAsarFlag, *uninstallOpenAsarFlag
-	switches := []*bool{&install, &update, &uninstall, &installOpenAsar, &uninstallOpenAsar}
+	install, uninstall, update, installOpenAsar, uninstallOpenAsar, doctor, inspectAsar := *installFlag, *uninstallFlag, *updateFlag, *installOpenAsarFlag, *uninstallOpenAsarFlag, *doctorFlag, *inspectAsarFlag
+	listBackups, restoreBackup := *listBackupsFlag, *restoreBackupFlag != ""
+	listBuilds, rollback, changelog := *listBuildsFlag, *rollbackFlag != "", *changelogFlag
+	switches := []*bool{&install, &update, &uninstall, &installOpenAsar, &uninstallOpenAsar, &doctor, &inspectAsar, &listBackups, &restoreBackup, &listBuilds, &rollback, &changelog}
 	if !SliceContainsFunc(switches, func(b *bool) bool { return *b }) {
 		interactive = true
 
@@ -113,6 +198,13 @@ func main() {
 			"Uninstall Vencord",
 			"Install OpenAsar",
 			"Uninstall OpenAsar",
+			"Diagnose Discord Installs",
+			"Inspect Asar",
+			"List Backups",
+			"Restore Backup",
+			"List Cached Builds",
+			"Roll Back Vencord",
+			"Show Changelog",
 			"View Help Menu",
 			"Update Vencord Installer",
 			"Quit",
@@ -140,23 +232,40 @@ func main() {
 		*switches[SliceIndex(choices, choice)] = true
 	}
 
+	if *dryRunFlag {
+		if doctor || inspectAsar || listBackups || restoreBackup || listBuilds || changelog {
+			die("The 'dry-run' flag can only be used with install, repair, uninstall, install-openasar, uninstall-openasar and rollback.")
+		}
+	}
+
+	if *offlineFlag != "" && !install && !update {
+		die("The 'offline' flag can only be used with install and repair.")
+	}
+
+	if *allFlag {
+		runForAll(install, uninstall, update, installOpenAsar, uninstallOpenAsar)
+	}
+
 	var err error
 	var errSilent error
 	if install {
+		printChangelog()
 		errSilent = PromptDiscord("patch", *locationFlag, *branchFlag).patch()
 	} else if uninstall {
 		errSilent = PromptDiscord("unpatch", *locationFlag, *branchFlag).unpatch()
 	} else if update {
-		Log.Info("Downloading latest Vencord files...")
-		err := installLatestBuilds()
-		Log.Info("Done!")
-		if err == nil {
+		printChangelog()
+		Log.Info(Ternary(LocalBuildsSource != "", "Installing Vencord files from "+LocalBuildsSource+"...", "Downloading latest Vencord files..."))
+		// already logged by installLatestBuilds
+		errSilent = InstallLatestBuilds()
+		if errSilent == nil {
+			Log.Info("Done!")
 			errSilent = PromptDiscord("repair", *locationFlag, *branchFlag).patch()
 		}
 	} else if installOpenAsar {
 		discord := PromptDiscord("patch", *locationFlag, *branchFlag)
 		if !discord.IsOpenAsar() {
-			err = discord.InstallOpenAsar()
+			err = discord.InstallOpenAsar(context.Background())
 		} else {
 			die("OpenAsar already installed")
 		}
@@ -167,8 +276,65 @@ func main() {
 		} else {
 			die("OpenAsar not installed")
 		}
+	} else if doctor {
+		installs := SliceMap(discords, func(d any) *DiscordInstall { return d.(*DiscordInstall) })
+		if WriteDoctorReport(os.Stdout, installs) {
+			exitFailure()
+		}
+	} else if listBackups {
+		var backups []*Backup
+		if backups, err = ListAllBackups(); err == nil {
+			err = WriteBackupList(os.Stdout, backups)
+		}
+	} else if restoreBackup {
+		var backup *Backup
+		if id := *restoreBackupFlag; id != "" {
+			backup, err = FindBackup(id)
+		} else {
+			backup, err = PromptBackup()
+		}
+		if err == nil {
+			Log.Info("Restoring backup", backup.Id, "of", backup.Install+"...")
+			err = RestoreBackup(backup)
+		}
+	} else if listBuilds {
+		var builds []*CachedBuild
+		if builds, err = ListBuilds(); err == nil {
+			err = WriteBuildList(os.Stdout, builds)
+		}
+	} else if rollback {
+		var build *CachedBuild
+		if version := *rollbackFlag; version != "" {
+			build, err = FindBuild(version)
+		} else {
+			build, err = PromptBuild()
+		}
+		if err == nil {
+			Log.Info("Rolling back to Vencord", build.Hash+"...")
+			err = ActivateBuild(build)
+		}
+	} else if changelog {
+		if interactive && !<-GithubDoneChan {
+			die("Can't show the changelog as fetching release data failed")
+		}
+		if InstalledHash == LatestHash {
+			Log.Info("Vencord is up to date")
+		} else {
+			var c *Changelog
+			if c, err = FetchChangelog(context.Background(), InstalledHash, LatestHash); err == nil {
+				fmt.Println(c)
+			}
+		}
+	} else if inspectAsar {
+		if file := flag.Arg(0); file != "" {
+			err = InspectAsar(os.Stdout, file)
+		} else {
+			err = InspectInstallAsars(os.Stdout, PromptDiscord("inspect", *locationFlag, *branchFlag))
+		}
 	}
 
+	printDryRunPlan()
+
 	if err != nil {
 		Log.Error(err)
 		exitFailure()
@@ -180,6 +346,73 @@ func main() {
 	exitSuccess()
 }
 
+func runForAll(install, uninstall, update, installOpenAsar, uninstallOpenAsar bool) {
+	switch {
+	case install, update:
+		if update || !IsUpToDate() {
+			printChangelog()
+			Log.Info(Ternary(LocalBuildsSource != "", "Installing Vencord files from "+LocalBuildsSource+"...", "Downloading latest Vencord files..."))
+			if err := InstallLatestBuilds(); err != nil {
+				exitFailure()
+			}
+		}
+		runForAllInstalls(Ternary(install, "patch", "repair"), Ternary(install, "patched", "repaired"), func(di *DiscordInstall) error {
+			return di.patch()
+		})
+	case uninstall:
+		runForAllInstalls("unpatch", "unpatched", func(di *DiscordInstall) error {
+			if !di.isPatched() {
+				return SkipInstall("not patched")
+			}
+			return di.unpatch()
+		})
+	case installOpenAsar:
+		runForAllInstalls("install OpenAsar on", "installed OpenAsar", func(di *DiscordInstall) error {
+			if di.IsOpenAsar() {
+				return SkipInstall("OpenAsar already installed")
+			}
+			return di.InstallOpenAsar(context.Background())
+		})
+	case uninstallOpenAsar:
+		runForAllInstalls("uninstall OpenAsar from", "uninstalled OpenAsar", func(di *DiscordInstall) error {
+			if !di.IsOpenAsar() {
+				return SkipInstall("OpenAsar not installed")
+			}
+			return di.UninstallOpenAsar()
+		})
+	default:
+		die("The 'all' flag can only be used with install, repair, uninstall, install-openasar and uninstall-openasar.")
+	}
+}
+
+func runForAllInstalls(action, done string, fn func(di *DiscordInstall) error) {
+	if len(discords) == 0 {
+		die("No Discord installs found. Hint: snap is not supported")
+	}
+
+	installs := SliceMap(discords, func(d any) *DiscordInstall { return d.(*DiscordInstall) })
+	results := RunForInstalls(installs, action, fn)
+
+	printDryRunPlan()
+	fmt.Println()
+	fmt.Println(FormatInstallResults(results, done))
+
+	if CountFailed(results) != 0 {
+		exitFailure()
+	}
+	exitSuccess()
+}
+
+func printDryRunPlan() {
+	plan := activePlan.Load()
+	if plan == nil {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Dry run, nothing was changed. Planned operations:")
+	fmt.Println(plan)
+}
+
 func exit(status int) {
 	if runtime.GOOS == "windows" && IsDoubleClickRun() && interactive {
 		fmt.Print("Press Enter to exit")
@@ -244,7 +477,7 @@ func PromptDiscord(action, dir, branch string) *DiscordInstall {
 	items := SliceMap(discords, func(d any) string {
 		install := d.(*DiscordInstall)
 		//goland:noinspection GoDeprecation
-		return fmt.Sprintf("%s - %s%s", strings.Title(install.branch), install.path, Ternary(install.isPatched, " [PATCHED]", ""))
+		return fmt.Sprintf("%s - %s%s", strings.Title(install.branch), install.path, install.patchLabel())
 	})
 	items = append(items, "Custom Location")
 
@@ -276,8 +509,83 @@ func PromptDiscord(action, dir, branch string) *DiscordInstall {
 	}
 }
 
+func PromptBackup() (*Backup, error) {
+	backups, err := ListAllBackups()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, errors.New("No backups found")
+	}
+
+	items := SliceMap(backups, (*Backup).Label)
+	i, _, err := (&promptui.Select{
+		Label: "Select backup to restore (Press Enter to confirm)",
+		Items: items,
+	}).Run()
+	handlePromptError(err)
+
+	return backups[i], nil
+}
+
+func PromptBuild() (*CachedBuild, error) {
+	builds, err := ListBuilds()
+	if err != nil {
+		return nil, err
+	}
+	if len(builds) == 0 {
+		return nil, errors.New("No cached builds found")
+	}
+
+	items := SliceMap(builds, (*CachedBuild).Label)
+	i, _, err := (&promptui.Select{
+		Label: "Select build to roll back to (Press Enter to confirm)",
+		Items: items,
+	}).Run()
+	handlePromptError(err)
+
+	return builds[i], nil
+}
+
+// InstallLatestBuilds downloads the latest builds, printing progress. Ctrl+C cancels the download
 func InstallLatestBuilds() error {
-	return installLatestBuilds()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := installLatestBuilds(ctx, printDownloadProgress)
+	if errors.Is(err, context.Canceled) {
+		Log.Warn("Download cancelled. Your previous Vencord files were kept")
+	}
+	return err
+}
+
+// printChangelog prints what changed since the installed version, if there is an update.
+// Failing to fetch it is not worth stopping the install over
+func printChangelog() {
+	if IsDryRun() || !CanShowChangelog() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	c, err := FetchChangelog(ctx, InstalledHash, LatestHash)
+	if err != nil {
+		Log.Warn(err)
+		return
+	}
+	fmt.Println(c)
+}
+
+func printDownloadProgress(e DownloadEvent) {
+	if !e.Done || e.Err != nil {
+		return
+	}
+
+	total := ""
+	if e.TotalSize > 0 {
+		total = fmt.Sprintf(", %d%% total", e.TotalDownloaded*100/e.TotalSize)
+	}
+	Log.Info(fmt.Sprintf("[%d/%d] Downloaded %s (%s%s)", e.FilesDone, e.FilesTotal, e.Name, FormatBytes(e.Downloaded), total))
 }
 
 func HandleScuffedInstall() {
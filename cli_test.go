@@ -0,0 +1,76 @@
+//go:build cli
+
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// setupOutdatedRelease makes the latest release differ from the installed build and serves its assets with handler
+func setupOutdatedRelease(t *testing.T, handler http.HandlerFunc) {
+	setupTestDirs(t)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	settings, release, latest, installed := Settings, ReleaseData, LatestHash, InstalledHash
+	t.Cleanup(func() { Settings, ReleaseData, LatestHash, InstalledHash = settings, release, latest, installed })
+
+	Settings.AssetMirrors, Settings.AssetFallbackUrls = nil, nil
+	InstalledHash, LatestHash = "1111111", "2222222"
+	ReleaseData = GithubRelease{Name: "Vencord 2222222", TagName: "v2"}
+	for _, name := range DefaultAssetManifest.RequiredFiles() {
+		ReleaseData.Assets = append(ReleaseData.Assets, GithubAsset{Name: name, DownloadURL: srv.URL + "/" + name})
+	}
+}
+
+func TestPatchFailsIfDownloadFails(t *testing.T) {
+	setupOutdatedRelease(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	di := &DiscordInstall{path: t.TempDir()}
+	err := di.patch()
+	if !errors.Is(err, ErrAlreadyReported) {
+		t.Fatalf("patch() = %v, want an already reported error", err)
+	}
+	assertNoFile(t, Patcher)
+}
+
+func TestPatchFailsIfDownloadIsCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't send ourselves an interrupt on windows")
+	}
+
+	requested := make(chan struct{}, 1)
+	setupOutdatedRelease(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	})
+
+	go func() {
+		<-requested
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(os.Interrupt)
+	}()
+
+	di := &DiscordInstall{path: t.TempDir()}
+	err := di.patch()
+	if !errors.Is(err, ErrAlreadyReported) {
+		t.Fatalf("patch() = %v, want an already reported error", err)
+	}
+	assertNoFile(t, Patcher)
+}
@@ -0,0 +1,184 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	path "path/filepath"
+	"strings"
+)
+
+// Endpoints can be overridden by BaseDir/config.json, environment variables and cli flags, in increasing priority
+
+type Config struct {
+	Channel              string   `json:"channel,omitempty"` // see channel.go
+	ReleaseUrls          []string `json:"releaseUrls,omitempty"`
+	AssetMirrors         []string `json:"assetMirrors,omitempty"`      // tried before the download url GitHub returns for an asset
+	AssetFallbackUrls    []string `json:"assetFallbackUrls,omitempty"` // tried after it. {tag} and {name} are replaced
+	InstallerReleaseUrls []string `json:"installerReleaseUrls,omitempty"`
+	OpenAsarUrls         []string `json:"openAsarUrls,omitempty"`
+	CompareUrls          []string `json:"compareUrls,omitempty"`     // see changelog.go
+	GithubToken          string   `json:"githubToken,omitempty"`     // see github_api.go
+	AllowUnverified      bool     `json:"allowUnverified,omitempty"` // install assets without checksum or signature
+}
+
+// GitHub has a very strict 60 req/h rate limit and some (mostly indian) isps block github for some reason,
+// so our fallback at https://vencord.dev/releases/project is tried second
+var Settings = Config{
+	ReleaseUrls:          []string{ReleaseUrl, ReleaseUrlFallback},
+	InstallerReleaseUrls: []string{InstallerReleaseUrl, InstallerReleaseUrlFallback},
+	OpenAsarUrls:         []string{OpenAsarDownloadLink},
+	CompareUrls:          []string{CompareUrl},
+}
+
+// Environment variables that override the config file. Each is a comma separated list of urls
+var configEnvVars = map[string]func(c *Config) *[]string{
+	"VENCORD_RELEASE_URLS":           func(c *Config) *[]string { return &c.ReleaseUrls },
+	"VENCORD_ASSET_MIRRORS":          func(c *Config) *[]string { return &c.AssetMirrors },
+	"VENCORD_ASSET_FALLBACK_URLS":    func(c *Config) *[]string { return &c.AssetFallbackUrls },
+	"VENCORD_INSTALLER_RELEASE_URLS": func(c *Config) *[]string { return &c.InstallerReleaseUrls },
+	"VENCORD_OPENASAR_URLS":          func(c *Config) *[]string { return &c.OpenAsarUrls },
+	"VENCORD_COMPARE_URLS":           func(c *Config) *[]string { return &c.CompareUrls },
+}
+
+func ConfigFile() string {
+	return path.Join(BaseDir, "config.json")
+}
+
+// LoadConfig applies the config file and environment variables to Settings. Must be called before anything is fetched.
+// If the config file or a variable is broken, an error is returned but everything else is still applied
+func LoadConfig() error {
+	errs := []error{loadConfigFile()}
+
+	for _, name := range SortedKeys(configEnvVars) {
+		if value := os.Getenv(name); value != "" {
+			Log.Debug("Using", name)
+			errs = append(errs, OverrideUrls(name, configEnvVars[name](&Settings), value))
+		}
+	}
+	if os.Getenv("VENCORD_ALLOW_UNVERIFIED") == "1" {
+		Settings.AllowUnverified = true
+	}
+	if token := os.Getenv("VENCORD_GITHUB_TOKEN"); token != "" {
+		Log.Debug("Using VENCORD_GITHUB_TOKEN")
+		Settings.GithubToken = strings.TrimSpace(token)
+	}
+	return errors.Join(errs...)
+}
+
+func loadConfigFile() error {
+	b, err := os.ReadFile(ConfigFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("Failed to read %s: %w", ConfigFile(), err)
+	}
+
+	var file Config
+	if err = json.Unmarshal(b, &file); err != nil {
+		return fmt.Errorf("Failed to parse %s: %w", ConfigFile(), err)
+	}
+	// empty urls would leave nothing to fetch from. merge ignores lists that end up empty
+	for _, field := range configEnvVars {
+		urls := field(&file)
+		*urls = SliceFilter(SliceMap(*urls, strings.TrimSpace), func(url string) bool { return url != "" })
+	}
+	if file.Channel != "" {
+		if file.Channel, err = ParseChannel(file.Channel); err != nil {
+			return fmt.Errorf("Invalid channel in %s: %w", ConfigFile(), err)
+		}
+	}
+	Log.Debug("Loaded config from", ConfigFile())
+	Settings.merge(&file)
+	return nil
+}
+
+// merge overrides everything that is set in other
+func (c *Config) merge(other *Config) {
+	if other.Channel != "" {
+		c.Channel = other.Channel
+	}
+	if other.GithubToken != "" {
+		c.GithubToken = other.GithubToken
+	}
+	if other.AllowUnverified {
+		c.AllowUnverified = true
+	}
+	for _, field := range configEnvVars {
+		if urls := *field(other); len(urls) != 0 {
+			*field(c) = urls
+		}
+	}
+}
+
+// ParseUrlList splits a comma separated list of urls
+func ParseUrlList(s string) []string {
+	return SliceFilter(SliceMap(strings.Split(s, ","), strings.TrimSpace), func(url string) bool { return url != "" })
+}
+
+// saveConfigValue sets key in the config file, keeping everything else in it as is
+func saveConfigValue(key string, value any) error {
+	values := make(map[string]json.RawMessage)
+	b, err := os.ReadFile(ConfigFile())
+	if err == nil {
+		if err = json.Unmarshal(b, &values); err != nil {
+			return fmt.Errorf("Failed to parse %s: %w", ConfigFile(), err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("Failed to read %s: %w", ConfigFile(), err)
+	}
+
+	if values[key], err = json.Marshal(value); err != nil {
+		return err
+	}
+	if b, err = json.MarshalIndent(values, "", "\t"); err != nil {
+		return err
+	}
+	if err = os.WriteFile(ConfigFile(), b, 0644); err != nil {
+		return fmt.Errorf("Failed to write %s: %w", ConfigFile(), err)
+	}
+	_ = FixOwnership(ConfigFile())
+	return nil
+}
+
+// OverrideUrls replaces urls with the comma separated list value, unless it is empty.
+// A value without any urls, like ",", is an error. name is the flag or variable it came from
+func OverrideUrls(name string, urls *[]string, value string) error {
+	if value == "" {
+		return nil
+	}
+	parsed := ParseUrlList(value)
+	if len(parsed) == 0 {
+		return fmt.Errorf("%s must contain at least one url", name)
+	}
+	*urls = parsed
+	return nil
+}
+
+// assetUrls returns the urls to download ass from, in the order they should be tried: the asset mirrors,
+// the url GitHub returns, the fallbacks from the asset manifest and finally the configured fallbacks
+func assetUrls(release *GithubRelease, ass GithubAsset) []string {
+	urls := SliceMap(Settings.AssetMirrors, func(mirror string) string {
+		return strings.TrimSuffix(mirror, "/") + "/" + ass.Name
+	})
+	if ass.DownloadURL != "" {
+		urls = append(urls, ass.DownloadURL)
+	}
+
+	r := strings.NewReplacer("{tag}", url.PathEscape(release.TagName), "{name}", url.PathEscape(ass.Name))
+	for _, fallback := range append(VencordAssets.FallbackUrls(ass.Name), Settings.AssetFallbackUrls...) {
+		if u := r.Replace(fallback); !SliceContains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
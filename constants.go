@@ -13,6 +13,7 @@ import (
 
 const ReleaseUrl = "https://api.github.com/repos/Vendicated/Vencord/releases/latest"
 const ReleaseUrlFallback = "https://vencord.dev/releases/vencord"
+const CompareUrl = "https://api.github.com/repos/Vendicated/Vencord/compare/{base}...{head}"
 const InstallerReleaseUrl = "https://api.github.com/repos/Vencord/Installer/releases/latest"
 const InstallerReleaseUrlFallback = "https://vencord.dev/releases/installer"
 
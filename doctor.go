@@ -0,0 +1,264 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	path "path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type DoctorStatus int
+
+const (
+	DoctorPass DoctorStatus = iota
+	DoctorWarn
+	DoctorFail
+)
+
+var doctorStatusNames = map[DoctorStatus]string{
+	DoctorPass: "PASS",
+	DoctorWarn: "WARN",
+	DoctorFail: "FAIL",
+}
+
+var doctorStatusColors = map[DoctorStatus]*color.Color{
+	DoctorPass: color.New(color.FgGreen),
+	DoctorWarn: color.New(color.FgYellow),
+	DoctorFail: color.New(color.FgRed),
+}
+
+type DoctorResult struct {
+	Check   string
+	Status  DoctorStatus
+	Message string
+	Fix     string // suggested fix, empty for passing checks
+}
+
+func checkPassed(check, message string) DoctorResult {
+	return DoctorResult{check, DoctorPass, message, ""}
+}
+
+func checkWarned(check, message, fix string) DoctorResult {
+	return DoctorResult{check, DoctorWarn, message, fix}
+}
+
+func checkFailed(check, message, fix string) DoctorResult {
+	return DoctorResult{check, DoctorFail, message, fix}
+}
+
+// Diagnose runs all checks on di
+func Diagnose(di *DiscordInstall) []DoctorResult {
+	di.updatePatchState()
+
+	results := []DoctorResult{
+		checkShim(di),
+		checkOriginalAsar(di),
+	}
+	if di.shim != nil {
+		results = append(results, checkPatcherPath(di))
+	}
+	results = append(results, checkDistFiles(), checkLeftovers(di))
+	if di.isFlatpak {
+		results = append(results, checkFlatpakOverride(di))
+	}
+	return results
+}
+
+func checkShim(di *DiscordInstall) DoctorResult {
+	const check = "Shim"
+	switch di.patchState {
+	case PatchStateUnpatched:
+		return checkWarned(check, "This install is not patched", "Install Vencord on it if you want to use Vencord there")
+	case PatchStateForeign:
+		return checkFailed(check, "app.asar was not written by the Vencord Installer. Another mod may have patched this install",
+			"Uninstall the other mod, or Reinstall / Repair Vencord to replace it")
+	}
+
+	if di.shim.IsLegacy() {
+		return checkWarned(check, "app.asar was written by an old version of the installer and won't fall back to stock Discord if Vencord breaks",
+			"Reinstall / Repair Vencord")
+	}
+	return checkPassed(check, fmt.Sprintf("app.asar is the Vencord shim, written by installer %s on %s",
+		di.shim.InstallerVersion, di.shim.PatchedAt.Local().Format("2006-01-02 15:04")))
+}
+
+func checkOriginalAsar(di *DiscordInstall) DoctorResult {
+	const check = "Original asar"
+	name := Ternary(di.patchState == PatchStateUnpatched, "app.asar", "_app.asar")
+	file := path.Join(di.resourcesDir(), name)
+
+	if !ExistsFile(file) {
+		return checkFailed(check, name+" is missing", restoreFix(di))
+	}
+
+	a, err := OpenAsar(file)
+	if err != nil {
+		return checkFailed(check, name+" is corrupt: "+err.Error(), restoreFix(di))
+	}
+	defer a.Close()
+
+	if !a.Exists("package.json") {
+		return checkFailed(check, name+" has no package.json", restoreFix(di))
+	}
+	return checkPassed(check, name+" is a valid asar archive")
+}
+
+// restoreFix suggests restoring the newest backup of di if there is one
+func restoreFix(di *DiscordInstall) string {
+	backups, _ := ListBackups(di.path)
+	if len(backups) == 0 {
+		return "Reinstall Discord"
+	}
+	return "Run with --restore-backup " + backups[0].Id + " to restore the backup from " +
+		backups[0].CreatedAt.Local().Format("2006-01-02 15:04") + ", or reinstall Discord"
+}
+
+func checkPatcherPath(di *DiscordInstall) DoctorResult {
+	const check = "Patcher"
+	if _, err := os.Stat(di.shim.Patcher); err != nil {
+		return checkFailed(check, "The shim loads "+di.shim.Patcher+", which is not accessible: "+err.Error(),
+			"Reinstall / Repair Vencord")
+	}
+	if path.Clean(di.shim.Patcher) != path.Clean(Patcher) {
+		return checkWarned(check, "The shim loads "+di.shim.Patcher+" instead of "+Patcher,
+			"Reinstall / Repair Vencord if this is not intentional")
+	}
+	return checkPassed(check, "The shim loads "+Patcher)
+}
+
+func checkDistFiles() DoctorResult {
+	const check = "Vencord files"
+	if m := InstalledManifest; m != nil {
+		if changed := m.ChangedFiles(); len(changed) != 0 {
+			return checkFailed(check, "Files of Vencord "+m.Hash+" are missing or were modified: "+strings.Join(changed, ", "),
+				"Reinstall / Repair Vencord to download them again")
+		}
+		return checkPassed(check, fmt.Sprintf("Vencord %s (%s) was installed by installer %s on %s and is unmodified",
+			m.Hash, m.describeSource(), m.InstallerVersion, m.InstalledAt.Local().Format("2006-01-02 15:04")))
+	}
+
+	var missing []string
+	for _, file := range VencordAssets.RequiredFiles() {
+		if !ExistsFile(path.Join(FilesDir, file)) {
+			missing = append(missing, file)
+		}
+	}
+
+	if len(missing) != 0 {
+		return checkFailed(check, FilesDir+" is missing "+strings.Join(missing, ", "), "Reinstall / Repair Vencord to download them again")
+	}
+	return checkWarned(check, FilesDir+" contains all Vencord files, but has no install manifest to verify them against",
+		"Reinstall / Repair Vencord")
+}
+
+func checkLeftovers(di *DiscordInstall) DoctorResult {
+	const check = "Leftovers"
+	var leftovers []string
+	for _, name := range []string{"app.asar.tmp", "_app.asar.tmp"} {
+		if file := path.Join(di.resourcesDir(), name); ExistsFile(file) {
+			leftovers = append(leftovers, file)
+		}
+	}
+	if ExistsFile(journalFile(di.resourcesDir())) {
+		leftovers = append(leftovers, journalFile(di.resourcesDir()))
+	}
+
+	if len(leftovers) != 0 {
+		return checkWarned(check, "Found leftovers of an interrupted operation: "+strings.Join(leftovers, ", "),
+			"Restart the installer to recover, or delete them manually")
+	}
+	return checkPassed(check, "No leftover temporary files")
+}
+
+func checkFlatpakOverride(di *DiscordInstall) DoctorResult {
+	const check = "Flatpak access"
+	fix := "Run: flatpak " + Ternary(di.isSystemFlatpak(), "", "--user ") + "override " + di.flatpakId() + " --filesystem=" + FilesDir
+
+	out, err := di.flatpakCommand("override", "--show", di.flatpakId()).Output()
+	if err != nil {
+		return checkFailed(check, "Failed to query Flatpak overrides: "+err.Error(), fix)
+	}
+
+	if !flatpakCanAccess(out, FilesDir) {
+		return checkFailed(check, di.flatpakId()+" has no access to "+FilesDir, fix)
+	}
+	return checkPassed(check, di.flatpakId()+" can access "+FilesDir)
+}
+
+// flatpakCanAccess checks whether the filesystems= line of `flatpak override --show` grants access to dir
+func flatpakCanAccess(overrides []byte, dir string) bool {
+	home, _ := os.UserHomeDir()
+
+	scanner := bufio.NewScanner(bytes.NewReader(overrides))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "filesystems=") {
+			continue
+		}
+
+		for _, fs := range strings.Split(strings.TrimPrefix(line, "filesystems="), ";") {
+			// strip access mode, e.g. /foo:ro
+			fs, _, _ = strings.Cut(fs, ":")
+			switch {
+			case fs == "" || strings.HasPrefix(fs, "!"): // !fs revokes access
+				continue
+			case fs == "host":
+				return true
+			case fs == "home" || fs == "~":
+				fs = home
+			case strings.HasPrefix(fs, "~/"):
+				fs = path.Join(home, fs[2:])
+			}
+
+			if fs != "" && path.IsAbs(fs) && isSubPath(fs, dir) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSubPath reports whether file is dir or inside of it
+func isSubPath(dir, file string) bool {
+	rel, err := path.Rel(dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(path.Separator))
+}
+
+// WriteDoctorReport diagnoses all installs and writes a report to w. Returns whether any check failed
+func WriteDoctorReport(w io.Writer, installs []*DiscordInstall) (failed bool) {
+	if len(installs) == 0 {
+		_, _ = fmt.Fprintln(w, "No Discord installs found.")
+		return true
+	}
+
+	for i, di := range installs {
+		if i != 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+
+		//goland:noinspection GoDeprecation
+		_, _ = fmt.Fprintf(w, "%s - %s\n", strings.Title(di.branch), di.path)
+		for _, res := range Diagnose(di) {
+			name := doctorStatusNames[res.Status]
+			_, _ = fmt.Fprintf(w, "  %s  %-15s %s\n", doctorStatusColors[res.Status].Sprint(name), res.Check, res.Message)
+			if res.Fix != "" {
+				_, _ = fmt.Fprintf(w, "        %-15s Fix: %s\n", "", res.Fix)
+			}
+			if res.Status == DoctorFail {
+				failed = true
+			}
+		}
+	}
+	return
+}
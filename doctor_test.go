@@ -0,0 +1,53 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestFlatpakCanAccess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("flatpak paths are unix paths")
+	}
+	t.Setenv("HOME", "/home/user")
+	dir := "/home/user/.config/Vencord/dist"
+
+	tests := []struct {
+		name      string
+		overrides string
+		want      bool
+	}{
+		{"no overrides", "", false},
+		{"other sections only", "[Context]\nsockets=wayland;\n", false},
+		{"host", "[Context]\nfilesystems=host;\n", true},
+		{"home", "[Context]\nfilesystems=home;\n", true},
+		{"home read only", "[Context]\nfilesystems=home:ro;\n", true},
+		{"exact dir", "[Context]\nfilesystems=/home/user/.config/Vencord/dist;\n", true},
+		{"parent dir", "[Context]\nfilesystems=/home/user/.config/Vencord;\n", true},
+		{"tilde path", "[Context]\nfilesystems=~/.config/Vencord:create;\n", true},
+		{"one of several", "[Context]\nfilesystems=xdg-download;/opt/foo;~/.config/Vencord;\n", true},
+		{"sub dir only", "[Context]\nfilesystems=/home/user/.config/Vencord/dist/sub;\n", false},
+		{"sibling with same prefix", "[Context]\nfilesystems=/home/user/.config/Venc;\n", false},
+		{"revoked", "[Context]\nfilesystems=!home;\n", false},
+		{"relative path", "[Context]\nfilesystems=.config/Vencord;\n", false},
+		{"unrelated dir", "[Context]\nfilesystems=/opt/Vencord;\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flatpakCanAccess([]byte(tt.overrides), dir); got != tt.want {
+				t.Errorf("flatpakCanAccess(%q) = %v, want %v", tt.overrides, got, tt.want)
+			}
+		})
+	}
+
+	// home only grants access to dirs in it
+	if flatpakCanAccess([]byte("filesystems=home;\n"), "/home/user2/.config/Vencord/dist") {
+		t.Error("home grants access to /home/user2")
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const DefaultDownloadConcurrency = 3
+
+type DownloadJob struct {
+	Name   string
+	URLs   []string // tried in order until one works
+	File   string
+	Verify func(file string) error // optional, called once the download finished
+}
+
+// DownloadEvent reports the progress of one file together with the totals of the whole run
+type DownloadEvent struct {
+	Name       string
+	Downloaded int64
+	Size       int64 // -1 if the server didn't tell
+	Done       bool
+	Err        error
+
+	TotalDownloaded int64
+	TotalSize       int64 // sum of all known sizes
+	FilesDone       int
+	FilesTotal      int
+}
+
+type DownloadManager struct {
+	Concurrency int
+	OnProgress  func(e DownloadEvent) // called from worker goroutines, but never concurrently
+
+	mu         sync.Mutex
+	downloaded []int64
+	sizes      []int64
+	filesDone  int
+}
+
+func NewDownloadManager(onProgress func(e DownloadEvent)) *DownloadManager {
+	return &DownloadManager{
+		Concurrency: DefaultDownloadConcurrency,
+		OnProgress:  onProgress,
+	}
+}
+
+// Run downloads all jobs with at most Concurrency at once. After the first failure, no new jobs are started.
+// The errors of all failed jobs are returned in the order of jobs
+func (m *DownloadManager) Run(ctx context.Context, jobs []DownloadJob) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m.downloaded = make([]int64, len(jobs))
+	m.sizes = make([]int64, len(jobs))
+	for i := range m.sizes {
+		m.sizes[i] = -1
+	}
+	m.filesDone = 0
+
+	errs := make([]error, len(jobs))
+	sem := make(chan struct{}, Ternary(m.Concurrency > 0, m.Concurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		i, job := i, job // Need to do this to not have the variable be overwritten halfway through
+
+		select {
+		case sem <- struct{}{}:
+			// select picks at random if a slot freed up after the context was cancelled
+			if ctx.Err() != nil {
+				<-sem
+				errs[i] = ctx.Err()
+				continue
+			}
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			errs[i] = m.runJob(ctx, i, job)
+			if errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Jobs that were only cancelled because another one failed are not interesting
+	if err := errors.Join(errs...); err != nil {
+		failed := SliceFilter(errs, func(err error) bool { return err != nil && !errors.Is(err, context.Canceled) })
+		if len(failed) == 0 {
+			return context.Canceled
+		}
+		return errors.Join(failed...)
+	}
+	return nil
+}
+
+func (m *DownloadManager) runJob(ctx context.Context, i int, job DownloadJob) error {
+	if ExistsFile(job.File) {
+		Log.Debug("Already downloaded", job.Name)
+	} else {
+		Log.Debug("Downloading file", job.Name)
+		err := downloadToFileFromMirrors(ctx, job.URLs, job.File, func(downloaded, size int64) {
+			m.report(i, job.Name, downloaded, size, false, nil)
+		})
+		if err != nil {
+			err = fmt.Errorf("Failed to download %s: %w", job.Name, err)
+			m.report(i, job.Name, -1, -1, true, err)
+			return err
+		}
+	}
+
+	if job.Verify != nil {
+		if err := job.Verify(job.File); err != nil {
+			m.report(i, job.Name, -1, -1, true, err)
+			return err
+		}
+	}
+
+	m.report(i, job.Name, -1, -1, true, nil)
+	return nil
+}
+
+// report updates the progress of job i. Negative values keep the last known ones
+func (m *DownloadManager) report(i int, name string, downloaded, size int64, done bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if downloaded >= 0 {
+		m.downloaded[i] = downloaded
+	}
+	if size >= 0 {
+		m.sizes[i] = size
+	}
+	if done {
+		m.filesDone++
+	}
+
+	if m.OnProgress == nil {
+		return
+	}
+
+	e := DownloadEvent{
+		Name:       name,
+		Downloaded: m.downloaded[i],
+		Size:       m.sizes[i],
+		Done:       done,
+		Err:        err,
+		FilesDone:  m.filesDone,
+		FilesTotal: len(m.sizes),
+	}
+	for j := range m.sizes {
+		e.TotalDownloaded += m.downloaded[j]
+		if m.sizes[j] > 0 {
+			e.TotalSize += m.sizes[j]
+		}
+	}
+	m.OnProgress(e)
+}
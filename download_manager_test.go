@@ -0,0 +1,123 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeJobs returns n jobs whose files already exist, so Run only calls verify for them
+func fakeJobs(t *testing.T, n int, verify func(i int) error) []DownloadJob {
+	dir := t.TempDir()
+	jobs := make([]DownloadJob, n)
+	for i := range jobs {
+		i := i
+		file := filepath.Join(dir, "asset"+strconv.Itoa(i))
+		writeTestFile(t, file, "")
+		jobs[i] = DownloadJob{
+			Name:   "asset" + strconv.Itoa(i),
+			File:   file,
+			Verify: func(string) error { return verify(i) },
+		}
+	}
+	return jobs
+}
+
+func TestDownloadManagerLimitsConcurrency(t *testing.T) {
+	var active, maxActive int32
+	jobs := fakeJobs(t, 8, func(int) error {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		return nil
+	})
+
+	var events []DownloadEvent
+	m := NewDownloadManager(func(e DownloadEvent) { events = append(events, e) })
+	m.Concurrency = 2
+	if err := m.Run(context.Background(), jobs); err != nil {
+		t.Fatal(err)
+	}
+	if maxActive != 2 {
+		t.Errorf("at most %d jobs ran at once, want 2", maxActive)
+	}
+	if len(events) != len(jobs) || events[len(events)-1].FilesDone != len(jobs) {
+		t.Errorf("got %d events, want one per job ending with all files done", len(events))
+	}
+}
+
+func TestDownloadManagerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started int32
+	jobs := fakeJobs(t, 6, func(int) error {
+		if atomic.AddInt32(&started, 1) == 2 {
+			cancel()
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	m := NewDownloadManager(nil)
+	m.Concurrency = 2
+	if err := m.Run(ctx, jobs); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v, want context.Canceled", err)
+	}
+	if started != 2 {
+		t.Errorf("%d jobs started, want none after cancelling", started)
+	}
+}
+
+func TestDownloadManagerErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		fail        map[int]time.Duration // job -> how long it runs before failing
+		want        string
+		wantStarted int32
+	}{
+		{"in order of jobs, not of failure", 4, map[int]time.Duration{1: 30 * time.Millisecond, 3: 0}, "asset1 failed\nasset3 failed", 4},
+		{"no new jobs after a failure", 1, map[int]time.Duration{0: 0}, "asset0 failed", 1},
+		{"cancelled jobs are left out", 2, map[int]time.Duration{1: 0}, "asset1 failed", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var started int32
+			jobs := fakeJobs(t, 4, func(i int) error {
+				atomic.AddInt32(&started, 1)
+				if d, ok := tt.fail[i]; ok {
+					time.Sleep(d)
+					return errors.New("asset" + strconv.Itoa(i) + " failed")
+				}
+				// wait long enough for the failures, which cancel the jobs that are still running
+				time.Sleep(50 * time.Millisecond)
+				return context.Canceled
+			})
+
+			m := NewDownloadManager(nil)
+			m.Concurrency = tt.concurrency
+			err := m.Run(context.Background(), jobs)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Run() = %q, want %q", err, tt.want)
+			}
+			if started != tt.wantStarted {
+				t.Errorf("%d jobs started, want %d", started, tt.wantStarted)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	path "path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DownloadAttempts = 4
+
+var ErrNoUrls = errors.New("No urls configured")
+
+// Delay before the first retry. Doubled for every following one
+var DownloadRetryDelay = time.Second
+
+// httpStatusError is returned for non-OK responses. Only some of them are worth retrying
+type httpStatusError struct {
+	code   int
+	status string
+}
+
+func (e *httpStatusError) Error() string {
+	return e.status
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		// retrying would only make it worse
+		return false
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500 || statusErr.code == http.StatusRequestTimeout || statusErr.code == http.StatusTooManyRequests
+	}
+	// network errors and truncated bodies
+	return true
+}
+
+// withRetries calls fn until it succeeds, fails with a non retryable error or DownloadAttempts is exhausted
+func withRetries(ctx context.Context, name string, fn func() error) error {
+	delay := DownloadRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == DownloadAttempts || !isRetryable(err) {
+			return err
+		}
+
+		Log.Warn(fmt.Sprintf("Failed to download %s (attempt %d of %d): %s. Retrying in %s", name, attempt, DownloadAttempts, err, delay))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// tryMirrors calls fn with each of urls in order until it succeeds. The error of the last url is returned
+func tryMirrors(ctx context.Context, urls []string, fn func(i int, url string) error) error {
+	if len(urls) == 0 {
+		return ErrNoUrls
+	}
+
+	var err error
+	for i, url := range urls {
+		if err = fn(i, url); err == nil || ctx.Err() != nil {
+			return err
+		}
+		if i != len(urls)-1 {
+			Log.Warn(fmt.Sprintf("Failed to fetch %s: %s. Trying %s", url, err, urls[i+1]))
+		}
+	}
+	return err
+}
+
+// downloadBytesFromMirrors downloads the first of urls that works into memory
+func downloadBytesFromMirrors(ctx context.Context, urls []string) (b []byte, err error) {
+	err = tryMirrors(ctx, urls, func(_ int, url string) (err error) {
+		b, err = downloadBytes(ctx, url)
+		return
+	})
+	return
+}
+
+// downloadBytes downloads url into memory. A Content-Length header is checked if the server sends one
+func downloadBytes(ctx context.Context, url string) (b []byte, err error) {
+	err = withRetries(ctx, url, func() error {
+		req, err := newRequest(ctx, url)
+		if err != nil {
+			return err
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if err = checkRateLimit(res); err != nil {
+			return err
+		}
+		if res.StatusCode >= 300 {
+			return &httpStatusError{res.StatusCode, res.Status}
+		}
+
+		b, err = io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		if res.ContentLength >= 0 && int64(len(b)) != res.ContentLength {
+			return errors.New("Unexpected end of input. Content-Length was " + strconv.FormatInt(res.ContentLength, 10) + ", but I only read " + strconv.Itoa(len(b)))
+		}
+		return nil
+	})
+	return
+}
+
+// downloadToFile downloads url to file. Data is written to file.part first, which is resumed with a Range
+// request if it already exists from an earlier attempt, and only renamed to file once complete.
+// progress is called with the bytes downloaded so far and the total size, or -1 if unknown
+func downloadToFile(ctx context.Context, url, file string, progress func(downloaded, size int64)) error {
+	part := file + ".part"
+	err := withRetries(ctx, path.Base(file), func() error {
+		return downloadPart(ctx, url, part, progress)
+	})
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(part + ".validator")
+	return os.Rename(part, file)
+}
+
+// removePart deletes a partial download of file
+func removePart(file string) {
+	_ = os.Remove(file + ".part")
+	_ = os.Remove(file + ".part.validator")
+}
+
+// rangeValidator returns the ETag or Last-Modified of res, which If-Range needs to only resume the same file
+func rangeValidator(res *http.Response) string {
+	// If-Range only works with strong ETags
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return res.Header.Get("Last-Modified")
+}
+
+// downloadToFileFromMirrors downloads the first of urls that works to file
+func downloadToFileFromMirrors(ctx context.Context, urls []string, file string, progress func(downloaded, size int64)) error {
+	return tryMirrors(ctx, urls, func(i int, url string) error {
+		if i != 0 {
+			// a partial download from another mirror can't safely be resumed
+			removePart(file)
+		}
+		return downloadToFile(ctx, url, file, progress)
+	})
+}
+
+// downloadPart downloads url to part, resuming it if it exists. The validator of the response is saved
+// next to it, so a resumed download is only appended to if the file didn't change in the meantime
+func downloadPart(ctx context.Context, url, part string, progress func(downloaded, size int64)) error {
+	validatorFile := part + ".validator"
+
+	var offset int64
+	validator, _ := os.ReadFile(validatorFile)
+	if stat, err := os.Stat(part); err == nil && len(validator) != 0 {
+		offset = stat.Size()
+	}
+
+	req, err := newRequest(ctx, url)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		Log.Debug("Resuming download of", url, "at byte", offset)
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", string(validator))
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// the part file is bigger than the file, so it's not from this download. Start over
+		_ = os.Remove(part)
+		_ = os.Remove(validatorFile)
+		return errors.New("Invalid partial download")
+	case res.StatusCode >= 300:
+		return &httpStatusError{res.StatusCode, res.Status}
+	default:
+		// the file changed or the server ignored the Range header, either way it sent everything
+		flags |= os.O_TRUNC
+		offset = 0
+		if validator := rangeValidator(res); validator != "" {
+			err = os.WriteFile(validatorFile, []byte(validator), 0644)
+		} else {
+			err = os.Remove(validatorFile)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	size := int64(-1)
+	if res.ContentLength >= 0 {
+		size = offset + res.ContentLength
+	}
+	var body io.Reader = res.Body
+	if progress != nil {
+		progress(offset, size)
+		body = &progressReader{res.Body, offset, size, progress}
+	}
+
+	read, err := io.Copy(out, body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if res.ContentLength >= 0 && read != res.ContentLength {
+		return errors.New("Unexpected end of input. Content-Length was " + strconv.FormatInt(res.ContentLength, 10) + ", but I only read " + strconv.FormatInt(read, 10))
+	}
+	return nil
+}
+
+type progressReader struct {
+	r          io.Reader
+	downloaded int64
+	size       int64
+	progress   func(downloaded, size int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.downloaded += int64(n)
+		p.progress(p.downloaded, p.size)
+	}
+	return n, err
+}
+
+// StagingDir is where a release is downloaded to before being swapped into FilesDir.
+// It is per release so partial downloads of an older release are never resumed
+func StagingDir(release string) string {
+	return path.Join(BaseDir, "staging", SanitizeFileName(release))
+}
+
+// cleanStagingDirs deletes the staging dirs of all releases except keep
+func cleanStagingDirs(keep string) {
+	root := path.Join(BaseDir, "staging")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if dir := path.Join(root, entry.Name()); dir != keep {
+			Log.Debug("Deleting old staging dir", dir)
+			_ = os.RemoveAll(dir)
+		}
+	}
+}
+
+// swapIntoDist replaces FilesDir with staging. The old dist is kept as dist.old until the swap is done,
+// so it can be put back by recoverDist if we die halfway through
+func swapIntoDist(staging string) error {
+	old := FilesDir + ".old"
+	recoverDist()
+	_ = os.RemoveAll(old)
+
+	if err := os.Rename(FilesDir, old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// On Windows, renaming the folder fails if any file in it is opened by Discord. Swap file by file instead
+		Log.Debug("Failed to move", FilesDir, "out of the way, swapping files one by one:", err)
+		return swapFiles(staging, old)
+	}
+
+	if err := os.Rename(staging, FilesDir); err != nil {
+		if restoreErr := os.Rename(old, FilesDir); restoreErr != nil {
+			Log.Error("Failed to restore", FilesDir, "from", old+":", restoreErr)
+		}
+		return err
+	}
+
+	if err := os.RemoveAll(old); err != nil {
+		Log.Warn("Failed to delete", old+":", err)
+	}
+	return nil
+}
+
+// swapFiles moves the files of staging into FilesDir one by one, and the ones they replace to old.
+// The renames are journaled, so they are rolled back if one fails or we die halfway through
+func swapFiles(staging, old string) error {
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(old, 0755); err != nil {
+		return err
+	}
+
+	var steps []JournalStep
+	for _, entry := range entries {
+		file := path.Join(FilesDir, entry.Name())
+		if ExistsFile(file) {
+			steps = append(steps, RenameStep(file, path.Join(old, entry.Name())))
+		}
+		steps = append(steps, RenameStep(path.Join(staging, entry.Name()), file))
+	}
+
+	journal, err := BeginJournal("update", FilesDir, steps, old, staging)
+	if err != nil {
+		return err
+	}
+	if err = journal.Run(nil); err != nil {
+		if rollbackErr := journal.Rollback(); rollbackErr != nil {
+			Log.Error("Failed to undo the partial update of", FilesDir+":", rollbackErr)
+		}
+		return err
+	}
+	journal.Commit()
+	return nil
+}
+
+// recoverDist puts back dist.old if an earlier swap died after moving dist away.
+// Swaps of single files are journaled instead and left to RecoverJournals
+func recoverDist() {
+	old := FilesDir + ".old"
+	if ExistsFile(journalFile(FilesDir)) {
+		return
+	}
+	if ExistsFile(old) && !ExistsFile(path.Join(FilesDir, "patcher.js")) {
+		if planned(PlanRename, old, FilesDir) {
+			return
+		}
+		Log.Warn("Restoring", FilesDir, "from an interrupted update")
+		_ = os.RemoveAll(FilesDir)
+		if err := os.Rename(old, FilesDir); err != nil {
+			Log.Error("Failed to restore", FilesDir+":", err)
+		}
+	}
+}
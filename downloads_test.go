@@ -0,0 +1,214 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	DownloadRetryDelay = time.Millisecond
+}
+
+// serveContent serves content with an ETag, so Range and If-Range requests work. Range headers are recorded
+func serveContent(t *testing.T, content *[]byte, etag *string, ranges *[]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", *etag)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(*content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownloadToFileResume(t *testing.T) {
+	tests := []struct {
+		name       string
+		part       string
+		validator  string
+		wantRanges []string
+	}{
+		{"fresh download", "", "", []string{""}},
+		{"resumes the same file", "hello ", `"v1"`, []string{"bytes=6-"}},
+		{"restarts if the file changed", "howdy ", `"v0"`, []string{"bytes=6-"}},
+		{"restarts without validator", "howdy ", "", []string{""}},
+		{"restarts if the part is too big", "hello world, how are you?", `"v1"`, []string{"bytes=25-", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, etag := []byte("hello world"), `"v1"`
+			var ranges []string
+			srv := serveContent(t, &content, &etag, &ranges)
+
+			file := filepath.Join(t.TempDir(), "patcher.js")
+			if tt.part != "" {
+				writeTestFile(t, file+".part", tt.part)
+			}
+			if tt.validator != "" {
+				writeTestFile(t, file+".part.validator", tt.validator)
+			}
+
+			if err := downloadToFile(context.Background(), srv.URL, file, nil); err != nil {
+				t.Fatal(err)
+			}
+			assertFile(t, file, "hello world")
+			assertNoFile(t, file+".part")
+			assertNoFile(t, file+".part.validator")
+			if len(ranges) != len(tt.wantRanges) {
+				t.Fatalf("sent Range headers %q, want %q", ranges, tt.wantRanges)
+			}
+			for i := range ranges {
+				if ranges[i] != tt.wantRanges[i] {
+					t.Errorf("sent Range headers %q, want %q", ranges, tt.wantRanges)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadPartSavesValidator(t *testing.T) {
+	content, etag := []byte("hello world"), `"v1"`
+	var ranges []string
+	srv := serveContent(t, &content, &etag, &ranges)
+
+	part := filepath.Join(t.TempDir(), "patcher.js.part")
+	if err := downloadPart(context.Background(), srv.URL, part, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, part, "hello world")
+	assertFile(t, part+".validator", `"v1"`)
+}
+
+func TestDownloadRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		wantRequests int32
+		wantErr      bool
+	}{
+		{"succeeds after server errors", 2, http.StatusBadGateway, 3, false},
+		{"gives up after DownloadAttempts", DownloadAttempts, http.StatusServiceUnavailable, DownloadAttempts, true},
+		{"doesn't retry not found", 1, http.StatusNotFound, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(atomic.AddInt32(&requests, 1)) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			b, err := downloadBytes(context.Background(), srv.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("downloadBytes() = %q, %v, want error %v", b, err, tt.wantErr)
+			}
+			if !tt.wantErr && string(b) != "ok" {
+				t.Errorf("downloadBytes() = %q, want ok", b)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestDownloadRetriesStopWhenCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := downloadBytes(ctx, srv.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("downloadBytes() = %v, want context.Canceled", err)
+	}
+}
+
+// setupSwap creates a dist with the old build and a staging dir with the new one
+func setupSwap(t *testing.T) (staging, old string) {
+	setupTestDirs(t)
+	writeTestFile(t, filepath.Join(FilesDir, "patcher.js"), "old patcher")
+	writeTestFile(t, filepath.Join(FilesDir, "renderer.js"), "old renderer")
+
+	staging = StagingDir("test")
+	writeTestFile(t, filepath.Join(staging, "patcher.js"), "new patcher")
+	writeTestFile(t, filepath.Join(staging, "renderer.js"), "new renderer")
+	writeTestFile(t, filepath.Join(staging, "preload.js"), "new preload")
+	return staging, FilesDir + ".old"
+}
+
+func TestSwapFiles(t *testing.T) {
+	staging, old := setupSwap(t)
+
+	if err := swapFiles(staging, old); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(FilesDir, "patcher.js"), "new patcher")
+	assertFile(t, filepath.Join(FilesDir, "renderer.js"), "new renderer")
+	assertFile(t, filepath.Join(FilesDir, "preload.js"), "new preload")
+	assertNoFile(t, staging)
+	assertNoFile(t, old)
+	assertNoFile(t, journalFile(FilesDir))
+}
+
+func TestSwapFilesRollsBackOnFailure(t *testing.T) {
+	staging, old := setupSwap(t)
+	// moving the old renderer.js out of the way fails, after patcher.js and preload.js were already swapped
+	writeTestFile(t, filepath.Join(old, "renderer.js", "in the way"), "")
+
+	if err := swapFiles(staging, old); err == nil {
+		t.Fatal("swapFiles succeeded")
+	}
+	assertFile(t, filepath.Join(FilesDir, "patcher.js"), "old patcher")
+	assertFile(t, filepath.Join(FilesDir, "renderer.js"), "old renderer")
+	assertNoFile(t, filepath.Join(FilesDir, "preload.js"))
+	assertFile(t, filepath.Join(staging, "patcher.js"), "new patcher")
+	assertFile(t, filepath.Join(staging, "preload.js"), "new preload")
+	assertNoFile(t, journalFile(FilesDir))
+}
+
+func TestRecoverDistLeavesJournaledSwapsAlone(t *testing.T) {
+	staging, old := setupSwap(t)
+	// as if we died after moving the old patcher.js away
+	if err := os.MkdirAll(old, 0755); err != nil {
+		t.Fatal(err)
+	}
+	j, err := BeginJournal("update", FilesDir, []JournalStep{
+		RenameStep(filepath.Join(FilesDir, "patcher.js"), filepath.Join(old, "patcher.js")),
+		CreateStep(filepath.Join(FilesDir, "patcher.js")),
+	}, old, staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = j.Run(func(string) error { return errors.New("crash") }); err == nil {
+		t.Fatal("Run succeeded, want the crash")
+	}
+
+	recoverDist()
+	assertFile(t, filepath.Join(FilesDir, "renderer.js"), "old renderer")
+
+	if err = RecoverJournals(); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(FilesDir, "patcher.js"), "old patcher")
+	assertFile(t, filepath.Join(FilesDir, "renderer.js"), "old renderer")
+}
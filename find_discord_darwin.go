@@ -34,14 +34,15 @@ func ParseDiscord(p, branch string) *DiscordInstall {
 	}
 
 	app := path.Join(resources, "app")
-	return &DiscordInstall{
+	di := &DiscordInstall{
 		path:             p,
 		branch:           branch,
 		appPath:          app,
-		isPatched:        ExistsFile(path.Join(resources, "_app.asar")),
 		isFlatpak:        false,
 		isSystemElectron: false,
 	}
+	di.updatePatchState()
+	return di
 }
 
 func FindDiscords() []any {
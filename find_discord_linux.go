@@ -71,7 +71,6 @@ func ParseDiscordNew(p, branch string, isFlatpak bool) *DiscordInstall {
 		return nil
 	}
 
-	isPatched := false
 	appPath := ""
 	for _, dir := range entries {
 		if dir.IsDir() && strings.HasPrefix(dir.Name(), "app-") {
@@ -82,7 +81,6 @@ func ParseDiscordNew(p, branch string, isFlatpak bool) *DiscordInstall {
 			app := path.Join(resources, "app")
 			if app > appPath {
 				appPath = app
-				isPatched = ExistsFile(path.Join(resources, "_app.asar"))
 			}
 		}
 	}
@@ -95,14 +93,15 @@ func ParseDiscordNew(p, branch string, isFlatpak bool) *DiscordInstall {
 		branch = GetBranch(p)
 	}
 
-	return &DiscordInstall{
+	di := &DiscordInstall{
 		path:             p,
 		branch:           branch,
 		appPath:          appPath,
-		isPatched:        isPatched,
 		isFlatpak:        isFlatpak,
 		isSystemElectron: false,
 	}
+	di.updatePatchState()
+	return di
 }
 
 func ParseDiscord(p, _ string) *DiscordInstall {
@@ -121,26 +120,22 @@ func ParseDiscord(p, _ string) *DiscordInstall {
 	resources := path.Join(p, "resources")
 	app := path.Join(resources, "app")
 
-	isPatched, isSystemElectron := false, false
-
-	if ExistsFile(resources) { // normal install
-		isPatched = ExistsFile(path.Join(resources, "_app.asar"))
-	} else if ExistsFile(path.Join(p, "app.asar")) { // System electron doesn't have resources folder
-		isSystemElectron = true
-		isPatched = ExistsFile(path.Join(p, "_app.asar.unpacked"))
-	} else {
+	// System electron doesn't have resources folder
+	isSystemElectron := !ExistsFile(resources)
+	if isSystemElectron && !ExistsFile(path.Join(p, "app.asar")) {
 		// Log.Warn("Tried to parse invalid Location:", p)
 		return nil
 	}
 
-	return &DiscordInstall{
+	di := &DiscordInstall{
 		path:             p,
 		branch:           GetBranch(name),
 		appPath:          app,
-		isPatched:        isPatched,
 		isFlatpak:        needsFlatpakResolve,
 		isSystemElectron: isSystemElectron,
 	}
+	di.updatePatchState()
+	return di
 }
 
 func FindDiscords() []any {
@@ -34,7 +34,6 @@ func ParseDiscord(p, branch string) *DiscordInstall {
 		return nil
 	}
 
-	isPatched := false
 	appPath := ""
 	for _, dir := range entries {
 		if dir.IsDir() && strings.HasPrefix(dir.Name(), "app-") {
@@ -45,7 +44,6 @@ func ParseDiscord(p, branch string) *DiscordInstall {
 			app := path.Join(resources, "app")
 			if app > appPath {
 				appPath = app
-				isPatched = ExistsFile(path.Join(resources, "_app.asar"))
 			}
 		}
 	}
@@ -58,14 +56,15 @@ func ParseDiscord(p, branch string) *DiscordInstall {
 		branch = GetBranch(p)
 	}
 
-	return &DiscordInstall{
+	di := &DiscordInstall{
 		path:             p,
 		branch:           branch,
 		appPath:          appPath,
-		isPatched:        isPatched,
 		isFlatpak:        false,
 		isSystemElectron: false,
 	}
+	di.updatePatchState()
+	return di
 }
 
 func FindDiscords() []any {
@@ -92,6 +91,9 @@ func PreparePatch(di *DiscordInstall) {
 	defer killLock.Unlock()
 	
 	name := windowsNames[di.branch]
+	if planned(PlanKill, name+".exe") {
+		return
+	}
 	Log.Debug("Trying to kill", name)
 	pid := findProcessIdByName(name + ".exe")
 	if pid == 0 {
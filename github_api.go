@@ -0,0 +1,73 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A token raises GitHub's api rate limit. It is only ever sent to GithubApiHost
+
+const GithubApiHost = "api.github.com"
+
+// RateLimitError is returned when GitHub refuses a request because the rate limit was exceeded
+type RateLimitError struct {
+	Reset time.Time // zero if GitHub didn't say
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "GitHub rate limit exceeded."
+	if !e.Reset.IsZero() {
+		msg += fmt.Sprintf(" It resets at %s (in %s).", e.Reset.Local().Format("15:04"), time.Until(e.Reset).Round(time.Minute))
+	}
+	if Settings.GithubToken == "" {
+		msg += " Set VENCORD_GITHUB_TOKEN to a GitHub token to raise the limit"
+	}
+	return msg
+}
+
+// newRequest creates a GET request with the headers every request needs
+func newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if Settings.GithubToken != "" && req.URL.Host == GithubApiHost {
+		req.Header.Set("Authorization", "Bearer "+Settings.GithubToken)
+	}
+	return req, nil
+}
+
+// checkRateLimit returns a RateLimitError if res was refused because of the rate limit
+func checkRateLimit(res *http.Response) error {
+	// only GitHub sends these, so errors from mirrors aren't mistaken for rate limits
+	remaining := res.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return nil
+	}
+	Log.Debug("GitHub rate limit remaining:", remaining)
+
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+
+	err := &RateLimitError{}
+	if secs, parseErr := strconv.ParseInt(res.Header.Get("Retry-After"), 10, 64); parseErr == nil {
+		err.Reset = time.Now().Add(time.Duration(secs) * time.Second)
+	} else if remaining != "0" {
+		// a 403 for some other reason, like a blocked token
+		return nil
+	} else if reset, parseErr := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64); parseErr == nil {
+		err.Reset = time.Unix(reset, 0)
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCheckRateLimit(t *testing.T) {
+	reset := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name      string
+		status    int
+		headers   map[string]string
+		wantErr   bool
+		wantReset time.Time // checked to the second, zero if GitHub didn't say
+	}{
+		{"ok", http.StatusOK, map[string]string{"X-RateLimit-Remaining": "59"}, false, time.Time{}},
+		{"mirror forbidden", http.StatusForbidden, nil, false, time.Time{}},
+		{"forbidden with requests left", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "10"}, false, time.Time{}},
+		{"exceeded", http.StatusForbidden, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+		}, true, reset},
+		{"exceeded without reset", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}, true, time.Time{}},
+		{"exceeded with bad reset", http.StatusTooManyRequests, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     "soon",
+		}, true, time.Time{}},
+		{"secondary limit", http.StatusForbidden, map[string]string{
+			"X-RateLimit-Remaining": "10",
+			"Retry-After":           "60",
+		}, true, time.Now().Add(time.Minute)},
+		{"too many requests", http.StatusTooManyRequests, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"Retry-After":           "120",
+		}, true, time.Now().Add(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.headers {
+				res.Header.Set(k, v)
+			}
+
+			err := checkRateLimit(res)
+			var rateErr *RateLimitError
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("checkRateLimit() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &rateErr) {
+				t.Fatalf("checkRateLimit() = %v, want a RateLimitError", err)
+			}
+			if d := rateErr.Reset.Sub(tt.wantReset); d < -time.Second || d > time.Second {
+				t.Errorf("Reset = %v, want %v", rateErr.Reset, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestNewRequestOnlySendsTokenToGithub(t *testing.T) {
+	prev := Settings.GithubToken
+	t.Cleanup(func() { Settings.GithubToken = prev })
+	Settings.GithubToken = "secret"
+
+	tests := map[string]string{
+		"https://api.github.com/repos/Vendicated/Vencord/releases/latest":             "Bearer secret",
+		"https://github.com/Vendicated/Vencord/releases/download/devbuild/patcher.js": "",
+		"https://mirror.example.com/api.github.com/patcher.js":                        "",
+	}
+	for url, want := range tests {
+		req, err := newRequest(context.Background(), url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("Authorization for %s = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -8,6 +8,8 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,18 +17,31 @@ import (
 	"net/http"
 	"os"
 	path "path/filepath"
-	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+type GithubAsset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+	Digest      string `json:"digest"` // "sha256:<hex>", only set by newer GitHub api versions
+}
+
 type GithubRelease struct {
-	Name    string `json:"name"`
-	TagName string `json:"tag_name"`
-	Assets  []struct {
-		Name        string `json:"name"`
-		DownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
+	Name       string        `json:"name"`
+	TagName    string        `json:"tag_name"`
+	Draft      bool          `json:"draft"`
+	Prerelease bool          `json:"prerelease"`
+	Assets     []GithubAsset `json:"assets"`
+	source     string        // the url it was fetched from
+	checkedAt  time.Time     // only set if fetching failed and the cached release is used, see release_cache.go
+	fetchErr   error         // why fetching failed, if checkedAt is set
+}
+
+// IsCached reports whether the release is from the cache because it couldn't be fetched
+func (r *GithubRelease) IsCached() bool {
+	return !r.checkedAt.IsZero()
 }
 
 var ReleaseData GithubRelease
@@ -37,16 +52,63 @@ var InstalledHash = "None"
 var LatestHash = "Unknown"
 var IsDevInstall bool
 
-func GetGithubRelease(url, fallbackUrl string) (*GithubRelease, error) {
+// stateLock guards the release data, the hashes and the patch state of installs, which background work changes
+// while the gui renders them. The gui holds it while rendering a frame, so the render thread must not take it again
+var stateLock sync.Mutex
+
+// releaseState is a consistent copy of the release data and hashes, for work that runs while they may change
+type releaseState struct {
+	Release           GithubRelease
+	LatestHash        string
+	InstalledHash     string
+	InstalledManifest *InstallManifest
+	LocalBuildsSource string
+}
+
+// currentReleaseState returns the release state without locking. Only the render thread, which holds stateLock
+// anyway, and the cli may use it
+func currentReleaseState() releaseState {
+	return releaseState{ReleaseData, LatestHash, InstalledHash, InstalledManifest, LocalBuildsSource}
+}
+
+// snapshotReleaseState returns the release state for background tasks
+func snapshotReleaseState() releaseState {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	return currentReleaseState()
+}
+
+// GetGithubRelease fetches the release json from the first of urls that works.
+// If none does, the last release fetched from any of them is returned
+func GetGithubRelease(urls []string) (*GithubRelease, error) {
+	var release *GithubRelease
+	err := tryMirrors(context.Background(), urls, func(_ int, url string) (err error) {
+		release, err = fetchGithubRelease(url)
+		return
+	})
+	if err != nil {
+		if cached, ok := cachedGithubRelease(urls); ok {
+			Log.Warn("Using the release last checked at", cached.checkedAt.Local().Format("2006-01-02 15:04"), "as fetching it failed:", err)
+			cached.fetchErr = err
+			return cached, nil
+		}
+	}
+	return release, err
+}
+
+func fetchGithubRelease(url string) (*GithubRelease, error) {
 	Log.Debug("Fetching", url)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := newRequest(context.Background(), url)
 	if err != nil {
 		Log.Error("Failed to create Request", err)
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", UserAgent)
+	cached := readCachedRelease(url)
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -56,29 +118,61 @@ func GetGithubRelease(url, fallbackUrl string) (*GithubRelease, error) {
 
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		Log.Debug(url, "is unchanged since", cached.CheckedAt)
+		cached.CheckedAt = time.Now().UTC()
+		writeCachedRelease(cached)
+		return parseGithubRelease(url, cached.Body)
+	}
+
+	if err = checkRateLimit(res); err != nil {
+		Log.Error(url, "refused the request:", err)
+		return nil, err
+	}
 	if res.StatusCode >= 300 {
-		isRateLimitedOrBlocked := res.StatusCode == 401 || res.StatusCode == 403 || res.StatusCode == 429
-		triedFallback := url == fallbackUrl
+		err = errors.New(res.Status)
+		Log.Error(url, "returned Non-OK status", res.Status)
+		return nil, err
+	}
 
-		// GitHub has a very strict 60 req/h rate limit and some (mostly indian) isps block github for some reason.
-		// If that is the case, try our fallback at https://vencord.dev/releases/project
-		if isRateLimitedOrBlocked && !triedFallback {
-			Log.Error(fmt.Sprintf("Failed to fetch %s (status code %d). Trying fallback url %s", url, res.StatusCode, fallbackUrl))
-			return GetGithubRelease(fallbackUrl, fallbackUrl)
-		}
+	var body json.RawMessage
 
-		err = errors.New(res.Status)
-		Log.Error(url, "returned Non-OK status", GithubError)
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+		Log.Error("Failed to decode GitHub JSON Response", err)
 		return nil, err
 	}
 
-	var data GithubRelease
+	release, err := parseGithubRelease(url, body)
+	if err != nil {
+		return nil, err
+	}
+	writeCachedRelease(&cachedRelease{Url: url, ETag: res.Header.Get("ETag"), CheckedAt: time.Now().UTC(), Body: body})
+	return release, nil
+}
 
-	if err = json.NewDecoder(res.Body).Decode(&data); err != nil {
+func parseGithubRelease(url string, body json.RawMessage) (*GithubRelease, error) {
+	// release lists, used by the prerelease channel, are sorted newest first
+	if trimmed := bytes.TrimSpace(body); len(trimmed) != 0 && trimmed[0] == '[' {
+		var releases []GithubRelease
+		if err := json.Unmarshal(body, &releases); err != nil {
+			Log.Error("Failed to decode GitHub JSON Response", err)
+			return nil, err
+		}
+		i := SliceIndexFunc(releases, func(r GithubRelease) bool { return !r.Draft })
+		if i == -1 {
+			return nil, errors.New(url + " returned no releases")
+		}
+		releases[i].source = url
+		return &releases[i], nil
+	}
+
+	var data GithubRelease
+	if err := json.Unmarshal(body, &data); err != nil {
 		Log.Error("Failed to decode GitHub JSON Response", err)
 		return nil, err
 	}
 
+	data.source = url
 	return &data, nil
 }
 
@@ -92,27 +186,29 @@ func InitGithubDownloader() {
 		return
 	}
 
-	go func() {
-		// Make sure UI updates once the request either finished or failed
-		defer func() {
-			GithubDoneChan <- GithubError == nil
-		}()
-
-		data, err := GetGithubRelease(ReleaseUrl, ReleaseUrlFallback)
-		if err != nil {
-			GithubError = err
-			return
-		}
+	if LocalBuildsSource != "" {
+		Log.Debug("Installing from", LocalBuildsSource+", not fetching release data")
+		GithubDoneChan <- true
+	} else {
+		go fetchLatestRelease()
+	}
 
-		ReleaseData = *data
+	recoverDist()
 
-		i := strings.LastIndex(data.Name, " ") + 1
-		LatestHash = data.Name[i:]
-		Log.Debug("Finished fetching GitHub Data")
-		Log.Debug("Latest hash is", LatestHash, "Local Install is", Ternary(LatestHash == InstalledHash, "up to date!", "outdated!"))
-	}()
+	m, err := ReadInstallManifest()
+	if err != nil {
+		Log.Warn("Ignoring broken install manifest:", err)
+	}
+	if m != nil {
+		stateLock.Lock()
+		InstalledManifest = m
+		InstalledHash = m.Hash
+		stateLock.Unlock()
+		Log.Debug("Installed build is", m.Hash, "from", m.Source)
+		return
+	}
 
-	// Check hash of installed version if exists
+	// Installs by older versions of the installer have no manifest, so check the hash in patcher.js
 	f, err := os.Open(Patcher)
 	if err != nil {
 		return
@@ -121,82 +217,270 @@ func InitGithubDownloader() {
 	defer f.Close()
 
 	Log.Debug("Found existing Vencord Install. Checking for hash...")
-	scanner := bufio.NewScanner(f)
+	if hash, ok := readPatcherHash(f); ok {
+		stateLock.Lock()
+		InstalledHash = hash
+		stateLock.Unlock()
+		Log.Debug("Existing hash is", hash)
+	} else {
+		Log.Debug("Didn't find hash")
+	}
+}
+
+func fetchLatestRelease() {
+	var err error
+	// Make sure UI updates once the request either finished or failed.
+	// The gui refetches when the channel changes, nobody waits for those
+	defer func() {
+		select {
+		case GithubDoneChan <- err == nil:
+		default:
+		}
+	}()
+
+	channel := CurrentChannel()
+	Log.Debug("Fetching release of channel", channel)
+	urls, err := ChannelReleaseUrls(Settings.ReleaseUrls, channel)
+	if err != nil {
+		stateLock.Lock()
+		GithubError = err
+		stateLock.Unlock()
+		return
+	}
+
+	data, err := GetGithubRelease(urls)
+	if err == nil {
+		err = CheckChannelRelease(data, channel)
+	}
+	if err != nil {
+		stateLock.Lock()
+		GithubError = err
+		stateLock.Unlock()
+		return
+	}
+
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	ReleaseData = *data
+
+	// an offline source may have been selected in the meantime
+	if LocalBuildsSource == "" {
+		LatestHash = releaseHash(data)
+	}
+	Log.Debug("Finished fetching GitHub Data")
+	Log.Debug("Latest hash is", LatestHash, "Local Install is", Ternary(LatestHash == InstalledHash, "up to date!", "outdated!"))
+}
+
+// releaseHash returns the Vencord commit hash of release, which is the last word of its name
+func releaseHash(release *GithubRelease) string {
+	if release.Name == "" {
+		return "Unknown"
+	}
+	i := strings.LastIndex(release.Name, " ") + 1
+	return release.Name[i:]
+}
+
+// readPatcherHash reads the version from the "// Vencord <hash>" header of patcher.js
+func readPatcherHash(r io.Reader) (string, bool) {
+	scanner := bufio.NewScanner(r)
 	if scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "// Vencord ") {
-			InstalledHash = line[11:]
-			Log.Debug("Existing hash is", InstalledHash)
-		} else {
-			Log.Debug("Didn't find hash")
+		if hash, ok := strings.CutPrefix(scanner.Text(), "// Vencord "); ok {
+			return hash, true
 		}
 	}
+	return "", false
 }
 
-func installLatestBuilds() (retErr error) {
+// installLatestBuilds downloads the latest release into FilesDir, or copies the builds from LocalBuildsSource
+// if one is set. onProgress may be nil
+func installLatestBuilds(ctx context.Context, onProgress func(e DownloadEvent)) error {
+	state := snapshotReleaseState()
+	if state.LocalBuildsSource != "" {
+		err := installLocalBuilds(state.LocalBuildsSource)
+		if err != nil {
+			Log.Error(err.Error())
+		}
+		return err
+	}
+
 	Log.Debug("Installing latest builds...")
 
+	// Everything is downloaded to a staging dir first and only moved into the build cache and activated
+	// once every asset downloaded and verified, so a failure keeps the old files
+	release := &state.Release
+	staging := StagingDir(release.TagName + "-" + state.LatestHash)
+
 	// create an empty package.json file in our files dir.
 	// without this, node will walk up the file tree and search for a package.json in the
 	// parent folders. This might lead to issues if the user for example has ~/package.json
 	// with type: "module" in it
-	pkgJsonFile := path.Join(FilesDir, "package.json")
-	err := os.WriteFile(pkgJsonFile, []byte("{}"), 0644)
+	pkgJsonFile := path.Join(staging, "package.json")
+
+	if IsDryRun() {
+		// the release's own asset manifest isn't fetched in dry runs, so this may miss new assets
+		assets, err := VencordAssets.SelectAssets(release.Assets)
+		if err != nil {
+			return err
+		}
+		planned(PlanWrite, pkgJsonFile)
+		for _, ass := range assets {
+			urls := assetUrls(release, ass)
+			if len(urls) == 0 {
+				return fmt.Errorf("Failed to download %s: %w", ass.Name, ErrNoUrls)
+			}
+			planned(PlanDownload, urls[0], path.Join(staging, ass.Name))
+		}
+		return installBuild(staging, latestBuildInfo(state))
+	}
+
+	checksums, err := FetchChecksums(ctx, release)
 	if err != nil {
-		Log.Warn("Failed to create", pkgJsonFile, err)
+		Log.Error(err.Error())
+		return err
 	}
+	manifest, err := FetchAssetManifest(ctx, release, checksums)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+	assets, err := manifest.SelectAssets(release.Assets)
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+	VencordAssets = manifest
 
-	var wg sync.WaitGroup
+	cleanStagingDirs(staging)
+	if err = os.MkdirAll(staging, 0755); err != nil {
+		return fmt.Errorf("Failed to create staging dir: %w", err)
+	}
 
-	for _, ass := range ReleaseData.Assets {
-		if strings.HasPrefix(ass.Name, "patcher.js") ||
-			strings.HasPrefix(ass.Name, "preload.js") ||
-			strings.HasPrefix(ass.Name, "renderer.js") ||
-			strings.HasPrefix(ass.Name, "renderer.css") {
-			wg.Add(1)
-			ass := ass // Need to do this to not have the variable be overwritten halfway through
-			go func() {
-				defer wg.Done()
-				Log.Debug("Downloading file", ass.Name)
+	err = os.WriteFile(pkgJsonFile, []byte("{}"), 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to create %s: %w", pkgJsonFile, err)
+	}
 
-				res, err := http.Get(ass.DownloadURL)
-				if err == nil && res.StatusCode >= 300 {
-					err = errors.New(res.Status)
-				}
-				if err != nil {
-					Log.Error("Failed to download", ass.Name+":", err)
-					retErr = err
-					return
-				}
-				outFile := path.Join(FilesDir, ass.Name)
-				out, err := os.OpenFile(outFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-				if err != nil {
-					Log.Error("Failed to create", outFile+":", err)
-					retErr = err
-					return
-				}
-				read, err := io.Copy(out, res.Body)
+	jobs := SliceMap(assets, func(ass GithubAsset) DownloadJob {
+		return DownloadJob{
+			Name: ass.Name,
+			URLs: assetUrls(release, ass),
+			File: path.Join(staging, ass.Name),
+			Verify: func(file string) error {
+				err := verifyAsset(ctx, release, ass, file, checksums)
 				if err != nil {
-					Log.Error("Failed to download to", outFile+":", err)
-					retErr = err
-					return
-				}
-				contentLength := res.Header.Get("Content-Length")
-				expected := strconv.FormatInt(read, 10)
-				if expected != contentLength {
-					err = errors.New("Unexpected end of input. Content-Length was " + contentLength + ", but I only read " + expected)
-					Log.Error(err.Error())
-					retErr = err
-					return
+					_ = os.Remove(file)
 				}
-			}()
+				return err
+			},
 		}
+	})
+	err = NewDownloadManager(onProgress).Run(ctx, jobs)
+	if err != nil && ctx.Err() == nil {
+		err = retryFromFallbackRelease(ctx, release, jobs, onProgress, err)
+	}
+	if err != nil {
+		Log.Error(err.Error())
+		return err
+	}
+
+	if err = installBuild(staging, latestBuildInfo(state)); err != nil {
+		Log.Error(err.Error())
+		return err
 	}
 
-	wg.Wait()
 	Log.Debug("Done!")
-	_ = FixOwnership(FilesDir)
+	return nil
+}
+
+// retryFromFallbackRelease retries the jobs that failed with err with the asset urls of the same release
+// from the other release urls, for example if GitHub's api works but downloading from it doesn't
+func retryFromFallbackRelease(ctx context.Context, release *GithubRelease, jobs []DownloadJob, onProgress func(e DownloadEvent), err error) error {
+	fallbacks := fallbackAssetUrls(release)
+
+	var retry []DownloadJob
+	added := false
+	for _, job := range jobs {
+		if ExistsFile(job.File) {
+			continue
+		}
+		urls := append([]string(nil), job.URLs...)
+		for _, u := range fallbacks[job.Name] {
+			if !SliceContains(urls, u) {
+				urls = append(urls, u)
+				added = true
+			}
+		}
+		job.URLs = urls
+		retry = append(retry, job)
+	}
+	if !added {
+		return err
+	}
+
+	Log.Warn("Retrying", len(retry), "failed downloads with the urls of the fallback release:", err)
+	return NewDownloadManager(onProgress).Run(ctx, retry)
+}
+
+// fallbackAssetUrls fetches release from the release urls it wasn't fetched from
+// and returns the download urls they list for its assets
+func fallbackAssetUrls(release *GithubRelease) map[string][]string {
+	urls, err := ChannelReleaseUrls(Settings.ReleaseUrls, CurrentChannel())
+	if err != nil {
+		return nil
+	}
+
+	fallbacks := make(map[string][]string)
+	for _, url := range urls {
+		if url == release.source {
+			continue
+		}
+		other, err := fetchGithubRelease(url)
+		if err != nil || other.TagName != release.TagName {
+			continue
+		}
+		for _, ass := range other.Assets {
+			if ass.DownloadURL != "" {
+				fallbacks[ass.Name] = append(fallbacks[ass.Name], ass.DownloadURL)
+			}
+		}
+	}
+	return fallbacks
+}
+
+func latestBuildInfo(state releaseState) BuildInfo {
+	return BuildInfo{
+		Hash:    state.LatestHash,
+		Tag:     state.Release.TagName,
+		Channel: CurrentChannel(),
+		Source:  state.Release.source,
+	}
+}
+
+// verifyAsset checks the downloaded file against the checksum and signature of ass
+func verifyAsset(ctx context.Context, release *GithubRelease, ass GithubAsset, file string, checksums Checksums) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return verifyAssetBytes(ctx, release, ass.Name, b, checksums)
+}
+
+// verifyAssetBytes checks the asset name of release against its checksum and signature
+func verifyAssetBytes(ctx context.Context, release *GithubRelease, name string, b []byte, checksums Checksums) error {
+	verified, err := checksums.Verify(name, b)
+	if err != nil {
+		return err
+	}
 
-	InstalledHash = LatestHash
-	return
+	if VencordSigningKeys.Enabled() {
+		sig, err := fetchSignature(ctx, release, name)
+		if err == nil {
+			err = VencordSigningKeys.Verify(name, b, sig)
+		}
+		if err != nil {
+			return fmt.Errorf("Signature verification failed, refusing to install: %w", err)
+		}
+		verified = true
+	}
+	return checkVerified(name, verified)
 }
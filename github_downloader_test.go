@@ -0,0 +1,63 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallLatestBuildsUsesFallbackReleaseAssets(t *testing.T) {
+	setupTestDirs(t)
+
+	var srv *httptest.Server
+	release := func(assetDir string) GithubRelease {
+		r := GithubRelease{Name: "Vencord abc1234", TagName: "v2"}
+		for _, name := range DefaultAssetManifest.RequiredFiles() {
+			r.Assets = append(r.Assets, GithubAsset{Name: name, DownloadURL: srv.URL + "/" + assetDir + "/" + name})
+		}
+		return r
+	}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/fallback":
+			_ = json.NewEncoder(w).Encode(release("mirror"))
+		case strings.HasPrefix(r.URL.Path, "/mirror/"):
+			_, _ = w.Write([]byte("// Vencord abc1234\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	settings, data, latest, installed, manifest := Settings, ReleaseData, LatestHash, InstalledHash, InstalledManifest
+	t.Cleanup(func() {
+		Settings, ReleaseData, LatestHash, InstalledHash, InstalledManifest = settings, data, latest, installed, manifest
+	})
+	Settings = Config{
+		Channel:         ChannelStable,
+		ReleaseUrls:     []string{srv.URL + "/releases/latest", srv.URL + "/fallback"},
+		AllowUnverified: true,
+	}
+	// as if GitHub's api worked, but its downloads didn't
+	ReleaseData = release("blocked")
+	ReleaseData.source = Settings.ReleaseUrls[0]
+	LatestHash = "abc1234"
+
+	if err := installLatestBuilds(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(FilesDir, "patcher.js"), "// Vencord abc1234\n")
+	if InstalledHash != "abc1234" {
+		t.Errorf("InstalledHash = %s, want abc1234", InstalledHash)
+	}
+}
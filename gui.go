@@ -10,8 +10,10 @@ package main
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"vencordinstaller/buildinfo"
@@ -26,6 +28,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 var (
@@ -33,6 +36,21 @@ var (
 	radioIdx        int
 	customChoiceIdx int
 
+	multiSelect      bool
+	selectedInstalls []bool
+
+	previewChanges bool
+	previewLock    sync.Mutex
+	previewPlan    string
+	previewApply   func()
+
+	useLocalBuilds   bool
+	localBuildsInput string
+
+	channelIdx      int32
+	channelTagInput string
+	fetchingRelease bool
+
 	customDir              string
 	autoCompleteDir        string
 	autoCompleteFile       string
@@ -48,6 +66,16 @@ var (
 	acceptedOpenAsar   bool
 	showedUpdatePrompt bool
 
+	recoveryLock        sync.Mutex
+	recoveryErr         error
+	showedRecoveryError bool
+
+	configErr         error
+	showedConfigError bool
+
+	downloadLock     sync.Mutex
+	downloadProgress *DownloadEvent // nil while not downloading
+
 	win *g.MasterWindow
 )
 
@@ -59,10 +87,17 @@ func init() {
 }
 
 func main() {
+	if configErr = LoadConfig(); configErr != nil {
+		Log.Error(configErr)
+	}
 	InitGithubDownloader()
+	InitSelfUpdater()
+	initChannelSelection()
+	recoveryErr = RecoverJournals()
 	discords = FindDiscords()
 
 	customChoiceIdx = len(discords)
+	selectedInstalls = make([]bool, len(discords))
 
 	go func() {
 		<-GithubDoneChan
@@ -105,7 +140,7 @@ func getChosenInstall() *DiscordInstall {
 	if radioIdx == customChoiceIdx {
 		choice = ParseDiscord(customDir, "")
 		if choice == nil {
-			g.OpenPopup("#invalid-custom-location")
+			openPopup("#invalid-custom-location")
 		}
 	} else {
 		choice = discords[radioIdx].(*DiscordInstall)
@@ -113,59 +148,394 @@ func getChosenInstall() *DiscordInstall {
 	return choice
 }
 
+// InstallLatestBuilds downloads the latest builds. Must be called from a task, whose cancel button cancels the download.
+// If some files can't be verified, the user is asked whether to install them anyway
 func InstallLatestBuilds() (err error) {
 	if IsDevInstall {
 		return
 	}
 
-	err = installLatestBuilds()
-	if err != nil {
+	src := snapshotReleaseState().LocalBuildsSource
+	err = installLatestBuildsInTask(src)
+	if errors.Is(err, ErrUnverified) && !Settings.AllowUnverified && confirmTask(
+		"Some of Vencord's files have no checksum or signature to verify them against,\n"+
+			"so they may have been tampered with. Only install them if you trust where they come from.", "Install Anyway",
+	) {
+		Settings.AllowUnverified = true
+		err = installLatestBuildsInTask(src)
+		Settings.AllowUnverified = false
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		ShowModal("Download Cancelled", "Your previous Vencord files were kept.")
+	case err != nil && src != "":
+		ShowModal("Uh Oh!", "Failed to install Vencord from "+src+":\n"+err.Error())
+	case err != nil:
 		ShowModal("Uh Oh!", "Failed to install the latest Vencord builds from GitHub:\n"+err.Error())
 	}
 	return
 }
 
+// installLatestBuildsInTask installs the latest builds, showing the progress of downloads. src is the local builds source, if any
+func installLatestBuildsInTask(src string) error {
+	if src != "" {
+		setTaskStep("Installing Vencord from "+src+"...", 0, 0)
+		return installLatestBuilds(taskContext(), nil)
+	}
+
+	downloadLock.Lock()
+	downloadProgress = &DownloadEvent{}
+	downloadLock.Unlock()
+
+	defer func() {
+		downloadLock.Lock()
+		downloadProgress = nil
+		downloadLock.Unlock()
+		g.Update()
+	}()
+
+	return installLatestBuilds(taskContext(), func(e DownloadEvent) {
+		downloadLock.Lock()
+		downloadProgress = &e
+		downloadLock.Unlock()
+		g.Update()
+	})
+}
+
+func getSelectedInstalls() []*DiscordInstall {
+	var installs []*DiscordInstall
+	for i, selected := range selectedInstalls {
+		if selected {
+			installs = append(installs, discords[i].(*DiscordInstall))
+		}
+	}
+	return installs
+}
+
+// runForInstalls runs fn on all installs and shows a summary. Must be called from a task
+func runForInstalls(ctx context.Context, installs []*DiscordInstall, action, done string, fn func(di *DiscordInstall) error) {
+	i := 0
+	results := RunForInstalls(installs, action, func(di *DiscordInstall) error {
+		if ctx.Err() != nil {
+			return SkipInstall("cancelled")
+		}
+		//goland:noinspection GoDeprecation
+		setTaskStep(fmt.Sprintf("%s %s (%d/%d)", strings.Title(action), di.path, i+1, len(installs)), i, len(installs))
+		i++
+		return fn(di)
+	})
+
+	for _, r := range results {
+		if errors.Is(r.Err, ErrJournalPending) {
+			showRecovery(r.Err)
+			return
+		}
+	}
+
+	failed := CountFailed(results)
+	ShowModal(Ternary(failed == 0, "Done!", "Some Installs Failed"), FormatInstallResults(results, done)+
+		"\n\nIf Discord is still open, fully close it first, then start it again.")
+}
+
+// targetInstalls returns the installs the buttons operate on
+func targetInstalls() []*DiscordInstall {
+	if multiSelect {
+		installs := getSelectedInstalls()
+		if len(installs) == 0 {
+			ShowModal("No Installs Selected", "Select at least one Discord install first.")
+		}
+		return installs
+	}
+	if choice := getChosenInstall(); choice != nil {
+		return []*DiscordInstall{choice}
+	}
+	return nil
+}
+
+// previewOperations plans fn on copies of the target installs and asks the user to confirm before calling apply.
+// Planning reads and hashes files, so it runs as a task
+func previewOperations(prepare func(ctx context.Context) error, fn func(di *DiscordInstall) error, apply func()) {
+	installs := targetInstalls()
+	if len(installs) == 0 {
+		return
+	}
+
+	runTask("Planning Changes", func(ctx context.Context) {
+		setTaskStep("Planning changes...", 0, 0)
+		plan, err := PlanOperations(func() error {
+			if prepare != nil {
+				if err := prepare(ctx); err != nil {
+					return err
+				}
+			}
+
+			var errs []error
+			for _, di := range installs {
+				c := *di
+				if err := fn(&c); err != nil && !isSkipError(err) {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		})
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			ShowModal("Failed to plan changes", err.Error())
+			return
+		}
+
+		previewLock.Lock()
+		previewPlan = plan.String()
+		previewApply = apply
+		previewLock.Unlock()
+		openPopup("#preview-changes")
+	})
+}
+
+func patchInstall(di *DiscordInstall) error {
+	return di.patch()
+}
+
+func unpatchInstall(di *DiscordInstall) error {
+	if !di.isPatched() {
+		return SkipInstall("not patched")
+	}
+	return di.unpatch()
+}
+
+func openAsarInstall(di *DiscordInstall) error {
+	if di.IsOpenAsar() {
+		return di.UninstallOpenAsar()
+	}
+	return di.InstallOpenAsar(context.Background())
+}
+
+// Must match the order of channelItems
+var channelValues = []string{ChannelStable, ChannelPrerelease, ChannelDevbuild, ChannelTagPrefix}
+var channelItems = []string{"Stable", "Prerelease", "Devbuild", "Pinned Tag"}
+
+func initChannelSelection() {
+	channel := CurrentChannel()
+	if tag, ok := strings.CutPrefix(channel, ChannelTagPrefix); ok {
+		channelIdx = int32(len(channelValues) - 1)
+		channelTagInput = tag
+	} else {
+		channelIdx = int32(SliceIndex(channelValues, channel))
+	}
+}
+
+func renderChannelSelection() g.Widget {
+	return g.Style().SetDisabled(IsDevInstall || LocalBuildsSource != "").To(
+		g.Combo("##channel", channelItems[channelIdx], channelItems, &channelIdx).
+			Size(150).
+			OnChange(onChannelChanged),
+		&CondWidget{channelValues[channelIdx] == ChannelTagPrefix, func() g.Widget {
+			return g.Row(
+				g.InputText(&channelTagInput).Hint("Tag").Size(150),
+				g.Style().
+					SetColor(g.StyleColorButton, DiscordBlue).
+					SetStyle(g.StyleVarFramePadding, 4, 4).
+					To(
+						g.Button("Apply").OnClick(onChannelChanged),
+					),
+			)
+		}, nil},
+		Tooltip("The release channel to install and repair Vencord from"),
+	)
+}
+
+func onChannelChanged() {
+	channel := channelValues[channelIdx]
+	if channel == ChannelTagPrefix {
+		if strings.TrimSpace(channelTagInput) == "" {
+			// wait until a tag was entered
+			return
+		}
+		channel += channelTagInput
+	}
+
+	channel, err := ParseChannel(channel)
+	if err != nil {
+		ShowModal("Invalid Channel", err.Error())
+		return
+	}
+	if channel == CurrentChannel() {
+		return
+	}
+
+	if err = SaveChannel(channel); err != nil {
+		ShowModal("Failed to remember the channel", err.Error()+"\nIt will only be used until you close the installer.")
+	}
+
+	GithubError = nil
+	LatestHash = "Unknown"
+	fetchingRelease = true
+	go func() {
+		fetchLatestRelease()
+		stateLock.Lock()
+		fetchingRelease = false
+		stateLock.Unlock()
+		g.Update()
+	}()
+}
+
+func onUseLocalBuildsChanged() {
+	if !useLocalBuilds {
+		go func() {
+			_ = SetLocalBuildsSource("")
+			g.Update()
+		}()
+	}
+}
+
+func handleLoadLocalBuilds() {
+	src := localBuildsInput
+	runTask("Loading Local Builds", func(ctx context.Context) {
+		setTaskStep("Reading "+src+"...", 0, 0)
+		if err := SetLocalBuildsSource(src); err != nil {
+			ShowModal("Invalid Local Builds", err.Error())
+			return
+		}
+		stateLock.Lock()
+		localBuildsInput = LocalBuildsSource
+		stateLock.Unlock()
+	})
+}
+
 func handlePatch() {
-	choice := getChosenInstall()
-	if choice != nil {
-		choice.Patch()
+	if previewChanges {
+		previewOperations(nil, patchInstall, handlePatchConfirmed)
+		return
+	}
+	handlePatchConfirmed()
+}
+
+func handleRepair() {
+	if CanShowChangelog() {
+		showChangelog(startRepair)
+		return
 	}
+	startRepair()
+}
+
+func startRepair() {
+	if previewChanges {
+		prepare := func(ctx context.Context) error {
+			// patch only downloads outdated builds, repair always does
+			if IsDevInstall || !snapshotReleaseState().IsUpToDate() {
+				return nil
+			}
+			return installLatestBuilds(ctx, nil)
+		}
+		previewOperations(prepare, patchInstall, handleRepairConfirmed)
+		return
+	}
+	handleRepairConfirmed()
+}
+
+func handleRepairConfirmed() {
+	installs := targetInstalls()
+	if len(installs) == 0 || CheckScuffedInstall() {
+		return
+	}
+
+	runTask("Repairing Vencord", func(ctx context.Context) {
+		if !IsDevInstall {
+			setTaskStep("Downloading Vencord...", 0, 0)
+			if err := InstallLatestBuilds(); err != nil {
+				return
+			}
+		}
+		patchInstalls(ctx, installs)
+	})
+}
+
+func handlePatchConfirmed() {
+	installs := targetInstalls()
+	if len(installs) == 0 || CheckScuffedInstall() {
+		return
+	}
+
+	runTask("Installing Vencord", func(ctx context.Context) {
+		patchInstalls(ctx, installs)
+	})
+}
+
+func patchInstalls(ctx context.Context, installs []*DiscordInstall) {
+	if multiSelect {
+		runForInstalls(ctx, installs, "patch", "patched", patchInstall)
+		return
+	}
+
+	setTaskStep("Patching "+installs[0].path, 0, 1)
+	installs[0].Patch()
 }
 
 func handleUnpatch() {
-	choice := getChosenInstall()
-	if choice != nil {
-		choice.Unpatch()
+	if previewChanges {
+		previewOperations(nil, unpatchInstall, handleUnpatchConfirmed)
+		return
 	}
+	handleUnpatchConfirmed()
+}
+
+func handleUnpatchConfirmed() {
+	installs := targetInstalls()
+	if len(installs) == 0 {
+		return
+	}
+
+	runTask("Uninstalling Vencord", func(ctx context.Context) {
+		if multiSelect {
+			runForInstalls(ctx, installs, "unpatch", "unpatched", unpatchInstall)
+			return
+		}
+
+		setTaskStep("Unpatching "+installs[0].path, 0, 1)
+		installs[0].Unpatch()
+	})
 }
 
 func handleOpenAsar() {
 	if acceptedOpenAsar || getChosenInstall().IsOpenAsar() {
-		handleOpenAsarConfirmed()
+		if previewChanges {
+			previewOperations(nil, openAsarInstall, handleOpenAsarConfirmed)
+		} else {
+			handleOpenAsarConfirmed()
+		}
 		return
 	}
 
-	g.OpenPopup("#openasar-confirm")
+	openPopup("#openasar-confirm")
 }
 
 func handleOpenAsarConfirmed() {
 	choice := getChosenInstall()
-	if choice != nil {
-		if choice.IsOpenAsar() {
+	if choice == nil {
+		return
+	}
+
+	if choice.IsOpenAsar() {
+		runTask("Uninstalling OpenAsar", func(ctx context.Context) {
+			setTaskStep("Restoring the original app.asar of "+choice.path, 0, 1)
 			if err := choice.UninstallOpenAsar(); err != nil {
 				handleErr(choice, err, "uninstall OpenAsar from")
 			} else {
-				g.OpenPopup("#openasar-unpatched")
-				g.Update()
+				openPopup("#openasar-unpatched")
 			}
-		} else {
-			if err := choice.InstallOpenAsar(); err != nil {
+		})
+	} else {
+		runTask("Installing OpenAsar", func(ctx context.Context) {
+			setTaskStep("Downloading OpenAsar...", 0, 1)
+			if err := choice.InstallOpenAsar(ctx); err != nil {
 				handleErr(choice, err, "install OpenAsar on")
 			} else {
-				g.OpenPopup("#openasar-patched")
-				g.Update()
+				openPopup("#openasar-patched")
 			}
-		}
+		})
 	}
 }
 
@@ -183,21 +553,60 @@ func handleErr(di *DiscordInstall, err error, action string) {
 		}
 	}
 
+	if errors.Is(err, context.Canceled) {
+		ShowModal("Cancelled", "Nothing was changed.")
+		return
+	}
+	if errors.Is(err, ErrJournalPending) {
+		showRecovery(err)
+		return
+	}
 	ShowModal("Failed to "+action+" this Install", err.Error())
 }
 
+// showRecovery offers to roll back or discard the journal that err is about. Safe to call from background tasks
+func showRecovery(err error) {
+	recoveryLock.Lock()
+	recoveryErr = err
+	recoveryLock.Unlock()
+	openPopup("#recovery")
+}
+
+func handleRetryRecovery() {
+	runTask("Rolling Back", func(ctx context.Context) {
+		setTaskStep("Rolling back interrupted operations...", 0, 1)
+		if err := RecoverJournals(); err != nil {
+			showRecovery(err)
+		} else {
+			ShowModal("Rolled Back", "The interrupted operations were rolled back. You can try again now.")
+		}
+	})
+}
+
+func handleDiscardJournals() {
+	runTask("Discarding Journals", func(ctx context.Context) {
+		if err := DiscardJournals(); err != nil {
+			ShowModal("Failed to discard the journals", err.Error())
+		} else {
+			ShowModal("Discarded", "The interrupted operations were forgotten. Their files were left as they are.")
+		}
+	})
+}
+
 func HandleScuffedInstall() {
-	g.OpenPopup("#scuffed-install")
+	openPopup("#scuffed-install")
 }
 
 func (di *DiscordInstall) Patch() {
 	if CheckScuffedInstall() {
 		return
 	}
-	if err := di.patch(); err != nil {
+	if err := di.patch(); errors.Is(err, ErrAlreadyReported) {
+		return
+	} else if err != nil {
 		handleErr(di, err, "patch")
 	} else {
-		g.OpenPopup("#patched")
+		openPopup("#patched")
 	}
 }
 
@@ -205,7 +614,7 @@ func (di *DiscordInstall) Unpatch() {
 	if err := di.unpatch(); err != nil {
 		handleErr(di, err, "unpatch")
 	} else {
-		g.OpenPopup("#unpatched")
+		openPopup("#unpatched")
 	}
 }
 
@@ -410,11 +819,105 @@ func UpdateModal() g.Widget {
 		)
 }
 
+func PreviewModal() g.Widget {
+	previewLock.Lock()
+	plan, apply := previewPlan, previewApply
+	previewLock.Unlock()
+
+	closeModal := func() {
+		previewLock.Lock()
+		previewApply = nil
+		previewLock.Unlock()
+		g.CloseCurrentPopup()
+	}
+
+	return g.Style().
+		SetStyle(g.StyleVarWindowPadding, 30, 30).
+		SetStyleFloat(g.StyleVarWindowRounding, 12).
+		To(
+			g.PopupModal("#preview-changes").
+				Flags(g.WindowFlagsNoTitleBar | g.WindowFlagsAlwaysAutoResize).
+				Layout(
+					g.Align(g.AlignCenter).To(
+						g.Style().SetFontSize(30).To(
+							g.Label("Planned Changes"),
+						),
+						g.Style().SetFontSize(20).To(
+							g.Label("The following operations will be performed:"),
+						),
+						g.Dummy(0, 10),
+						g.Label(plan),
+						g.Dummy(0, 20),
+						g.Row(
+							g.Button("Apply").
+								OnClick(func() {
+									closeModal()
+									if apply != nil {
+										apply()
+									}
+								}).
+								Size(100, 30),
+							g.Button("Cancel").
+								OnClick(closeModal).
+								Size(100, 30),
+						),
+					),
+				),
+		)
+}
+
+func RecoveryModal() g.Widget {
+	recoveryLock.Lock()
+	err := recoveryErr
+	recoveryLock.Unlock()
+
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+
+	return g.Style().
+		SetStyle(g.StyleVarWindowPadding, 30, 30).
+		SetStyleFloat(g.StyleVarWindowRounding, 12).
+		To(
+			g.PopupModal("#recovery").
+				Flags(g.WindowFlagsNoTitleBar | g.WindowFlagsAlwaysAutoResize).
+				Layout(
+					g.Align(g.AlignCenter).To(
+						g.Style().SetFontSize(30).To(
+							g.Label("Failed to recover an interrupted operation"),
+						),
+						g.Style().SetFontSize(20).To(
+							g.Label(message+"\n\n"+
+								"Retry rolling it back, or discard its journal once you fixed the files listed in it yourself.\n"+
+								"Discarding leaves those files as they are."),
+						),
+						g.Dummy(0, 20),
+						g.Row(
+							g.Button("Retry Rollback").
+								OnClick(func() {
+									g.CloseCurrentPopup()
+									handleRetryRecovery()
+								}).
+								Size(140, 30),
+							g.Button("Discard").
+								OnClick(func() {
+									g.CloseCurrentPopup()
+									handleDiscardJournals()
+								}).
+								Size(100, 30),
+							g.Button("Close").
+								OnClick(g.CloseCurrentPopup).
+								Size(100, 30),
+						),
+					),
+				),
+		)
+}
+
+// ShowModal shows an info modal. Safe to call from background tasks
 func ShowModal(title, desc string) {
-	modalTitle = title
-	modalMessage = desc
-	modalId++
-	g.OpenPopup("#modal" + strconv.Itoa(modalId))
+	queuePopup(pendingPopup{title: title, message: desc})
 }
 
 func renderInstaller() g.Widget {
@@ -430,7 +933,21 @@ func renderInstaller() g.Widget {
 
 	if CanUpdateSelf() && !showedUpdatePrompt {
 		showedUpdatePrompt = true
-		g.OpenPopup("#update-prompt")
+		openPopup("#update-prompt")
+	}
+
+	if !showedRecoveryError {
+		showedRecoveryError = true
+		recoveryLock.Lock()
+		if recoveryErr != nil {
+			openPopup("#recovery")
+		}
+		recoveryLock.Unlock()
+	}
+
+	if configErr != nil && !showedConfigError {
+		showedConfigError = true
+		ShowModal("Failed to load config", configErr.Error()+"\nIt was ignored.")
 	}
 
 	layout := g.Layout{
@@ -465,18 +982,51 @@ func renderInstaller() g.Widget {
 			g.RangeBuilder("Discords", discords, func(i int, v any) g.Widget {
 				d := v.(*DiscordInstall)
 				//goland:noinspection GoDeprecation
-				text := strings.Title(d.branch) + " - " + d.path
-				if d.isPatched {
-					text += " [PATCHED]"
+				text := strings.Title(d.branch) + " - " + d.path + d.patchLabel()
+				if multiSelect {
+					return g.Checkbox(text, &selectedInstalls[i])
 				}
 				return g.RadioButton(text, radioIdx == i).
 					OnChange(makeRadioOnChange(i))
 			}),
 
-			g.RadioButton("Custom Install Location", radioIdx == customChoiceIdx).
-				OnChange(makeRadioOnChange(customChoiceIdx)),
+			&CondWidget{!multiSelect, func() g.Widget {
+				return g.RadioButton("Custom Install Location", radioIdx == customChoiceIdx).
+					OnChange(makeRadioOnChange(customChoiceIdx))
+			}, nil},
 		),
 
+		g.Style().SetFontSize(20).To(
+			g.Row(
+				&CondWidget{len(discords) > 1, func() g.Widget {
+					return g.Row(
+						g.Checkbox("Select multiple installs", &multiSelect),
+						Tooltip("Install, repair or uninstall Vencord on several installs at once"),
+					)
+				}, nil},
+				g.Checkbox("Preview changes", &previewChanges),
+				Tooltip("Show which files would be changed and ask for confirmation first"),
+				g.Checkbox("Install from local files", &useLocalBuilds).OnChange(onUseLocalBuildsChanged),
+				Tooltip("Install Vencord from a folder, .zip or .tar.gz instead of downloading it, for example on machines without internet access"),
+			),
+		),
+
+		&CondWidget{useLocalBuilds, func() g.Widget {
+			return g.Style().SetFontSize(20).To(
+				g.Row(
+					g.InputText(&localBuildsInput).Hint("Folder, .zip or .tar.gz containing Vencord's dist files").
+						Size(w-16-120),
+					g.Style().
+						SetColor(g.StyleColorButton, DiscordBlue).
+						To(
+							g.Button("Load").
+								OnClick(handleLoadLocalBuilds).
+								Size(100, 0),
+						),
+				),
+			)
+		}, nil},
+
 		g.Dummy(0, 5),
 		g.Style().
 			SetStyle(g.StyleVarFramePadding, 16, 16).
@@ -530,7 +1080,7 @@ func renderInstaller() g.Widget {
 			g.Row(
 				g.Style().
 					SetColor(g.StyleColorButton, DiscordGreen).
-					SetDisabled(GithubError != nil).
+					SetDisabled((GithubError != nil || fetchingRelease) && LocalBuildsSource == "").
 					To(
 						g.Button("Install").
 							OnClick(handlePatch).
@@ -539,19 +1089,10 @@ func renderInstaller() g.Widget {
 					),
 				g.Style().
 					SetColor(g.StyleColorButton, DiscordBlue).
-					SetDisabled(GithubError != nil).
+					SetDisabled((GithubError != nil || fetchingRelease) && LocalBuildsSource == "").
 					To(
 						g.Button("Reinstall / Repair").
-							OnClick(func() {
-								if IsDevInstall {
-									handlePatch()
-								} else {
-									err := InstallLatestBuilds()
-									if err == nil {
-										handlePatch()
-									}
-								}
-							}).
+							OnClick(handleRepair).
 							Size((w-40)/4, 50),
 						Tooltip("Reinstall & Update Vencord"),
 					),
@@ -565,11 +1106,12 @@ func renderInstaller() g.Widget {
 					),
 				g.Style().
 					SetColor(g.StyleColorButton, Ternary(isOpenAsar, DiscordRed, DiscordGreen)).
+					SetDisabled(multiSelect).
 					To(
 						g.Button(Ternary(isOpenAsar, "Uninstall OpenAsar", Ternary(currentDiscord != nil, "Install OpenAsar", "(Un-)Install OpenAsar"))).
 							OnClick(handleOpenAsar).
 							Size((w-40)/4, 50),
-						Tooltip("Manage OpenAsar"),
+						Tooltip(Ternary(multiSelect, "OpenAsar can only be managed for one install at a time", "Manage OpenAsar")),
 					),
 			),
 		),
@@ -593,7 +1135,14 @@ func renderInstaller() g.Widget {
 		InfoModal("#invalid-custom-location", "Invalid Location", "The specified location is not a valid Discord install.\nMake sure you select the base folder.\n\nHint: Discord snap is not supported. use flatpak or .deb"),
 		InfoModal("#modal"+strconv.Itoa(modalId), modalTitle, modalMessage),
 
+		// right next to the modals, so the popups are opened with the same id stack they are rendered with
+		g.Custom(openPendingPopups),
+
 		UpdateModal(),
+		PreviewModal(),
+		RecoveryModal(),
+		ChangelogModal(),
+		TaskModal(),
 	}
 
 	return layout
@@ -619,6 +1168,9 @@ func renderErrorCard(col color.Color, message string, height float32) g.Widget {
 }
 
 func loop() {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
 	g.PushWindowPadding(48, 48)
 
 	g.SingleWindow().
@@ -659,14 +1211,36 @@ func loop() {
 				}, nil},
 				g.Dummy(0, 10),
 				g.Label("Installer Version: "+buildinfo.InstallerTag+" ("+buildinfo.InstallerGitHash+")"+Ternary(IsSelfOutdated, " - OUTDATED", "")),
-				g.Label("Local Vencord Version: "+InstalledHash),
+				g.Row(
+					g.Label("Local Vencord Version: "+InstalledHash+" ("+InstalledChannel()+")"),
+					renderChannelSelection(),
+				),
 				&CondWidget{
-					GithubError == nil,
+					GithubError == nil || LocalBuildsSource != "",
 					func() g.Widget {
 						if IsDevInstall {
 							return g.Label("Not updating Vencord due to being in DevMode")
 						}
-						return g.Label("Latest Vencord Version: " + LatestHash)
+						if LocalBuildsSource != "" {
+							return g.Label("Local Builds Version: " + LatestHash + " (" + LocalBuildsSource + ")")
+						}
+						latest := g.Row(
+							g.Label("Latest Vencord Version: "+LatestHash),
+							&CondWidget{CanShowChangelog(), func() g.Widget {
+								return g.Button("Show Changes").OnClick(func() { showChangelog(nil) })
+							}, nil},
+						)
+						if ReleaseData.IsCached() {
+							return g.Column(
+								latest,
+								renderErrorCard(
+									DiscordYellow,
+									"Couldn't reach GitHub, so this is the latest version as of "+ReleaseData.checkedAt.Local().Format("2006-01-02 15:04")+": "+ReleaseData.fetchErr.Error(),
+									40,
+								),
+							)
+						}
+						return latest
 					}, func() g.Widget {
 						return renderErrorCard(DiscordRed, "Failed to fetch Info from GitHub: "+GithubError.Error(), 40)
 					},
@@ -0,0 +1,113 @@
+//go:build !cli
+
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	g "github.com/AllenDang/giu"
+)
+
+var (
+	changelogLock  sync.Mutex
+	changelog      *Changelog // the last fetched changelog, reused while the versions don't change
+	changelogText  string
+	changelogApply func()
+)
+
+// showChangelog fetches the changes between the installed and the latest version and shows them.
+// If apply is set, the modal offers to continue with it. Failing to fetch the changelog doesn't prevent that
+func showChangelog(apply func()) {
+	base, head := InstalledHash, LatestHash
+	runTask("Fetching Changelog", func(ctx context.Context) {
+		setTaskStep("Fetching changes since "+base+"...", 0, 0)
+
+		changelogLock.Lock()
+		c := changelog
+		changelogLock.Unlock()
+
+		var text string
+		if c == nil || c.Base != base || c.Head != head {
+			var err error
+			if c, err = FetchChangelog(ctx, base, head); err != nil {
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+				Log.Warn(err)
+				text = err.Error()
+			}
+		}
+
+		changelogLock.Lock()
+		if c != nil {
+			changelog = c
+			text = c.String()
+		}
+		changelogText = text
+		changelogApply = apply
+		changelogLock.Unlock()
+		openPopup("#changelog")
+	})
+}
+
+func ChangelogModal() g.Widget {
+	changelogLock.Lock()
+	text, apply := changelogText, changelogApply
+	changelogLock.Unlock()
+
+	closeModal := func() {
+		changelogLock.Lock()
+		changelogApply = nil
+		changelogLock.Unlock()
+		g.CloseCurrentPopup()
+	}
+
+	var buttons []g.Widget
+	if apply != nil {
+		buttons = append(buttons,
+			g.Button("Repair").
+				OnClick(func() {
+					closeModal()
+					apply()
+				}).
+				Size(100, 30),
+		)
+	}
+	buttons = append(buttons,
+		g.Button(Ternary(apply != nil, "Cancel", "Close")).
+			OnClick(closeModal).
+			Size(100, 30),
+	)
+
+	return g.Style().
+		SetStyle(g.StyleVarWindowPadding, 30, 30).
+		SetStyleFloat(g.StyleVarWindowRounding, 12).
+		To(
+			g.PopupModal("#changelog").
+				Flags(g.WindowFlagsNoTitleBar | g.WindowFlagsAlwaysAutoResize).
+				Layout(
+					g.Align(g.AlignCenter).To(
+						g.Style().SetFontSize(30).To(
+							g.Label("What's New"),
+						),
+						g.Style().SetFontSize(20).To(
+							g.Label("Changes between "+InstalledHash+" and "+LatestHash),
+						),
+						g.Dummy(0, 10),
+						g.Child().Size(600, 300).Layout(
+							g.Label(text).Wrapped(true),
+						),
+						g.Dummy(0, 20),
+						g.Row(buttons...),
+					),
+				),
+		)
+}
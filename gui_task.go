@@ -0,0 +1,260 @@
+//go:build !cli
+
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	g "github.com/AllenDang/giu"
+)
+
+// Long running operations run in the background while a modal shows their progress
+
+type guiTask struct {
+	title     string
+	step      string
+	done      int
+	total     int
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelled bool
+	question  *taskQuestion // shown instead of the progress while set
+}
+
+type taskQuestion struct {
+	message string
+	accept  string
+	answer  chan bool
+}
+
+type pendingPopup struct {
+	id      string
+	title   string // only for ShowModal
+	message string
+}
+
+var (
+	taskLock      sync.Mutex
+	currentTask   *guiTask
+	pendingPopups []pendingPopup
+)
+
+// runTask runs fn in the background. Does nothing if another task is already running
+func runTask(title string, fn func(ctx context.Context)) {
+	taskLock.Lock()
+	if currentTask != nil {
+		taskLock.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	currentTask = &guiTask{title: title, ctx: ctx, cancel: cancel}
+	// first, as the popups after it wait for the task to finish
+	pendingPopups = append([]pendingPopup{{id: "#task"}}, pendingPopups...)
+	taskLock.Unlock()
+	g.Update()
+
+	go func() {
+		defer func() {
+			taskLock.Lock()
+			currentTask = nil
+			taskLock.Unlock()
+			cancel()
+			g.Update()
+		}()
+		fn(ctx)
+	}()
+}
+
+// reply answers the question. Only the first answer counts
+func (q *taskQuestion) reply(ok bool) {
+	select {
+	case q.answer <- ok:
+	default:
+	}
+}
+
+// confirmTask asks the user a yes or no question in the task modal and blocks until they answer.
+// Must be called from a task. Returns false if the task is cancelled
+func confirmTask(message, accept string) bool {
+	q := &taskQuestion{message: message, accept: accept, answer: make(chan bool, 1)}
+
+	taskLock.Lock()
+	task := currentTask
+	if task == nil {
+		taskLock.Unlock()
+		return false
+	}
+	task.question = q
+	taskLock.Unlock()
+	g.Update()
+
+	defer func() {
+		taskLock.Lock()
+		task.question = nil
+		taskLock.Unlock()
+		g.Update()
+	}()
+
+	select {
+	case ok := <-q.answer:
+		return ok
+	case <-task.ctx.Done():
+		return false
+	}
+}
+
+// taskContext returns the context of the running task, which is cancelled by its cancel button
+func taskContext() context.Context {
+	taskLock.Lock()
+	defer taskLock.Unlock()
+	if currentTask == nil {
+		return context.Background()
+	}
+	return currentTask.ctx
+}
+
+// setTaskStep updates the text and progress shown by the task modal
+func setTaskStep(step string, done, total int) {
+	taskLock.Lock()
+	if currentTask != nil {
+		currentTask.step = step
+		currentTask.done, currentTask.total = done, total
+	}
+	taskLock.Unlock()
+	g.Update()
+}
+
+func openPopup(id string) {
+	queuePopup(pendingPopup{id: id})
+}
+
+func queuePopup(p pendingPopup) {
+	taskLock.Lock()
+	pendingPopups = append(pendingPopups, p)
+	taskLock.Unlock()
+	g.Update()
+}
+
+// openPendingPopups opens queued popups. Must be called inside the window the modals are rendered in,
+// as popup ids depend on the id stack
+func openPendingPopups() {
+	taskLock.Lock()
+	defer taskLock.Unlock()
+
+	for i, p := range pendingPopups {
+		// wait for the task to finish, otherwise its modal would be replaced
+		if currentTask != nil && p.id != "#task" {
+			pendingPopups = pendingPopups[i:]
+			return
+		}
+
+		id := p.id
+		if id == "" {
+			modalTitle = p.title
+			modalMessage = p.message
+			modalId++
+			id = "#modal" + strconv.Itoa(modalId)
+		}
+		g.OpenPopup(id)
+	}
+	pendingPopups = nil
+}
+
+func TaskModal() g.Widget {
+	taskLock.Lock()
+	task := currentTask
+	var t guiTask
+	if task != nil {
+		t = *task
+	}
+	taskLock.Unlock()
+
+	downloadLock.Lock()
+	var download *DownloadEvent
+	if downloadProgress != nil {
+		e := *downloadProgress
+		download = &e
+	}
+	downloadLock.Unlock()
+
+	var fraction float32
+	step := t.step
+	switch {
+	case download != nil:
+		if download.TotalSize > 0 {
+			fraction = float32(download.TotalDownloaded) / float32(download.TotalSize)
+		}
+		step = fmt.Sprintf("Downloading Vencord... %d/%d files, %s", download.FilesDone, download.FilesTotal, FormatBytes(download.TotalDownloaded))
+	case t.total > 0:
+		fraction = float32(t.done) / float32(t.total)
+	}
+
+	status := []g.Widget{
+		g.Style().SetFontSize(20).To(
+			g.Label(Ternary(t.cancelled, "Cancelling...", step)),
+		),
+		g.Dummy(0, 10),
+		g.ProgressBar(fraction).Size(400, 0),
+	}
+	cancel := g.Button("Cancel").
+		OnClick(func() {
+			taskLock.Lock()
+			defer taskLock.Unlock()
+			if currentTask != nil {
+				currentTask.cancelled = true
+				currentTask.cancel()
+			}
+		}).
+		Size(100, 30)
+	buttons := []g.Widget{cancel}
+
+	if q := t.question; q != nil {
+		status = []g.Widget{
+			g.Style().SetFontSize(20).To(
+				g.Label(q.message),
+			),
+		}
+		buttons = []g.Widget{
+			g.Button(q.accept).
+				OnClick(func() { q.reply(true) }).
+				Size(150, 30),
+			g.Button("Cancel").
+				OnClick(func() { q.reply(false) }).
+				Size(100, 30),
+		}
+	}
+
+	return g.Style().
+		SetStyle(g.StyleVarWindowPadding, 30, 30).
+		SetStyleFloat(g.StyleVarWindowRounding, 12).
+		To(
+			g.PopupModal("#task").
+				Flags(g.WindowFlagsNoTitleBar|g.WindowFlagsAlwaysAutoResize).
+				Layout(
+					g.Custom(func() {
+						if task == nil {
+							g.CloseCurrentPopup()
+						}
+					}),
+					g.Align(g.AlignCenter).To(
+						g.Style().SetFontSize(30).To(
+							g.Label(t.title),
+						),
+						g.Column(status...),
+						g.Dummy(0, 20),
+						g.Style().SetDisabled(t.cancelled).To(
+							g.Row(buttons...),
+						),
+					),
+				),
+		)
+}
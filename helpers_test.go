@@ -0,0 +1,55 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupTestDirs points BaseDir and FilesDir at a temporary directory for the duration of the test
+func setupTestDirs(t *testing.T) {
+	t.Helper()
+	baseDir, filesDir, patcher := BaseDir, FilesDir, Patcher
+	t.Cleanup(func() { BaseDir, FilesDir, Patcher = baseDir, filesDir, patcher })
+
+	BaseDir = t.TempDir()
+	FilesDir = filepath.Join(BaseDir, "dist")
+	Patcher = filepath.Join(FilesDir, "patcher.js")
+	if err := os.MkdirAll(FilesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFile(t *testing.T, file, want string) {
+	t.Helper()
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Errorf("%s: %v", file, err)
+	} else if string(b) != want {
+		t.Errorf("%s contains %q, want %q", file, b, want)
+	}
+}
+
+func assertNoFile(t *testing.T, file string) {
+	t.Helper()
+	if _, err := os.Lstat(file); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s still exists", file)
+	}
+}
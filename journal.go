@@ -0,0 +1,300 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	path "path/filepath"
+	"time"
+)
+
+// Operations journal their steps first, so they can be rolled back if the installer dies halfway through
+
+const (
+	JournalStepRename = "rename"
+	JournalStepCreate = "create"
+)
+
+type JournalStep struct {
+	Kind string `json:"kind"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	Done bool   `json:"done"`
+}
+
+func RenameStep(from, to string) JournalStep {
+	return JournalStep{Kind: JournalStepRename, From: from, To: to}
+}
+
+// CreateStep creates a new file. Rolling it back deletes the file again
+func CreateStep(file string) JournalStep {
+	return JournalStep{Kind: JournalStepCreate, To: file}
+}
+
+type Journal struct {
+	Operation string        `json:"operation"`
+	Dir       string        `json:"dir"`
+	StartedAt time.Time     `json:"startedAt"`
+	Steps     []JournalStep `json:"steps"`
+	Cleanup   []string      `json:"cleanup,omitempty"` // deleted once all steps are done
+	file      string
+	corrupt   error // set if the journal file couldn't be parsed
+}
+
+// ErrJournalPending is returned if an earlier operation on the same dir was interrupted and couldn't be rolled back
+var ErrJournalPending = errors.New("Another operation did not finish")
+
+func JournalDir() string {
+	return path.Join(BaseDir, "journal")
+}
+
+func journalFile(dir string) string {
+	sum := sha256.Sum256([]byte(path.Clean(dir)))
+	return path.Join(JournalDir(), hex.EncodeToString(sum[:8])+".json")
+}
+
+// BeginJournal persists the planned steps. Nothing must be touched if this fails
+func BeginJournal(operation, dir string, steps []JournalStep, cleanup ...string) (*Journal, error) {
+	j := &Journal{
+		Operation: operation,
+		Dir:       dir,
+		StartedAt: time.Now(),
+		Steps:     steps,
+		Cleanup:   cleanup,
+		file:      journalFile(dir),
+	}
+
+	if ExistsFile(j.file) {
+		return nil, fmt.Errorf("%w on %s and could not be rolled back. Its journal is %s. "+
+			"Retry rolling it back, or discard the journal once you fixed its files yourself", ErrJournalPending, dir, j.file)
+	}
+
+	if planned(PlanWrite, j.file) {
+		return j, nil
+	}
+
+	if !ExistsFile(JournalDir()) {
+		if err := os.MkdirAll(JournalDir(), 0755); err != nil {
+			return nil, fmt.Errorf("Failed to create journal directory: %w", err)
+		}
+		_ = FixOwnership(JournalDir())
+	}
+
+	if err := j.save(); err != nil {
+		return nil, fmt.Errorf("Failed to write journal: %w", err)
+	}
+	return j, nil
+}
+
+func (j *Journal) save() error {
+	if IsDryRun() {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(j, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	tmp := j.file + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.file)
+}
+
+// Run executes all steps in order and stops at the first failure. create is called for CreateSteps
+func (j *Journal) Run(create func(file string) error) error {
+	for i := range j.Steps {
+		step := &j.Steps[i]
+		if step.Done {
+			continue
+		}
+
+		var err error
+		switch step.Kind {
+		case JournalStepRename:
+			Log.Debug("Renaming", step.From, "to", step.To)
+			if !planned(PlanRename, step.From, step.To) {
+				err = CheckIfErrIsCauseItsBusyRn(os.Rename(step.From, step.To))
+			}
+		case JournalStepCreate:
+			if !planned(PlanWrite, step.To) {
+				err = create(step.To)
+			}
+		default:
+			err = errors.New("Unknown journal step " + step.Kind)
+		}
+		if err != nil {
+			Log.Error(err.Error())
+			return err
+		}
+
+		step.Done = true
+		if err = j.save(); err != nil {
+			Log.Warn("Failed to update journal", j.file+":", err)
+		}
+	}
+	return nil
+}
+
+// Commit finishes a successful operation
+func (j *Journal) Commit() {
+	for _, file := range j.Cleanup {
+		if planned(PlanRemove, file) {
+			continue
+		}
+		if err := os.RemoveAll(file); err != nil {
+			Log.Warn("Failed to delete", file+". This is whatever but you might want to delete it manually.", err)
+		}
+	}
+
+	if planned(PlanRemove, j.file) {
+		return
+	}
+	if err := os.Remove(j.file); err != nil {
+		Log.Warn("Failed to delete journal", j.file+":", err)
+	}
+}
+
+// Rollback undoes all steps that were (possibly) taken, in reverse order
+func (j *Journal) Rollback() error {
+	// Steps before the first unfinished one are done. The unfinished one may or may not have happened
+	// depending on when we crashed, so check the file system for it
+	last := SliceIndexFunc(j.Steps, func(s JournalStep) bool { return !s.Done })
+	if last == -1 {
+		last = len(j.Steps) - 1
+	}
+
+	for i := last; i >= 0; i-- {
+		step := j.Steps[i]
+		switch step.Kind {
+		case JournalStepRename:
+			if !step.Done && (ExistsFile(step.From) || !ExistsFile(step.To)) {
+				continue
+			}
+			Log.Debug("Renaming", step.To, "back to", step.From)
+			if err := os.Rename(step.To, step.From); err != nil {
+				return CheckIfErrIsCauseItsBusyRn(err)
+			}
+		case JournalStepCreate:
+			if !ExistsFile(step.To) {
+				continue
+			}
+			Log.Debug("Deleting", step.To)
+			if err := os.RemoveAll(step.To); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Remove(j.file); err != nil {
+		Log.Warn("Failed to delete journal", j.file+":", err)
+	}
+	return nil
+}
+
+// readJournals reads all journals left behind by interrupted operations
+func readJournals() ([]*Journal, error) {
+	entries, err := os.ReadDir(JournalDir())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var journals []*Journal
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		file := path.Join(JournalDir(), entry.Name())
+		b, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		j := &Journal{file: file}
+		j.corrupt = json.Unmarshal(b, j)
+		journals = append(journals, j)
+	}
+	return journals, errors.Join(errs...)
+}
+
+// isFinished reports whether all steps were done, so only the cleanup is missing
+func (j *Journal) isFinished() bool {
+	return !SliceContainsFunc(j.Steps, func(s JournalStep) bool { return !s.Done })
+}
+
+// ReportJournals only logs what RecoverJournals would do. Used for dry runs
+func ReportJournals() error {
+	journals, err := readJournals()
+	for _, j := range journals {
+		switch {
+		case j.corrupt != nil:
+			Log.Warn("Would delete corrupt journal", j.file+":", j.corrupt)
+		case j.isFinished():
+			Log.Warn("Would finish interrupted", j.Operation, "of", j.Dir)
+		default:
+			Log.Warn("Would roll back interrupted", j.Operation, "of", j.Dir)
+		}
+	}
+	return err
+}
+
+// RecoverJournals finishes or rolls back operations that were interrupted by a crash
+func RecoverJournals() error {
+	journals, err := readJournals()
+
+	errs := []error{err}
+	for _, j := range journals {
+		if j.corrupt != nil {
+			Log.Warn("Deleting corrupt journal", j.file+":", j.corrupt)
+			_ = os.Remove(j.file)
+			continue
+		}
+
+		if j.isFinished() {
+			Log.Info("Finishing interrupted", j.Operation, "of", j.Dir)
+			j.Commit()
+			continue
+		}
+
+		Log.Warn("Rolling back interrupted", j.Operation, "of", j.Dir)
+		if err = j.Rollback(); err != nil {
+			errs = append(errs, fmt.Errorf("Failed to roll back interrupted %s of %s (journal %s): %w", j.Operation, j.Dir, j.file, err))
+		} else {
+			Log.Info("Successfully rolled back", j.Dir)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DiscardJournals forgets all interrupted operations without touching their files. For when rolling them back
+// keeps failing and the files were fixed by hand
+func DiscardJournals() error {
+	journals, err := readJournals()
+
+	errs := []error{err}
+	for _, j := range journals {
+		Log.Warn("Discarding journal", j.file, "of interrupted", j.Operation, "of", j.Dir)
+		if err = os.Remove(j.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("Failed to discard journal %s: %w", j.file, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupJournalTest returns a resources dir containing app.asar
+func setupJournalTest(t *testing.T) string {
+	setupTestDirs(t)
+	resources := t.TempDir()
+	writeTestFile(t, filepath.Join(resources, "app.asar"), "original")
+	return resources
+}
+
+func patchSteps(resources string) []JournalStep {
+	return []JournalStep{
+		RenameStep(filepath.Join(resources, "app.asar"), filepath.Join(resources, "_app.asar")),
+		CreateStep(filepath.Join(resources, "app.asar")),
+	}
+}
+
+func TestRecoverJournalsRollsBackInterruptedPatch(t *testing.T) {
+	resources := setupJournalTest(t)
+
+	j, err := BeginJournal("patch", resources, patchSteps(resources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the new app.asar was written, but we died before the step was marked as done
+	err = j.Run(func(file string) error {
+		writeTestFile(t, file, "patched")
+		return errors.New("crash")
+	})
+	if err == nil {
+		t.Fatal("Run succeeded, want the crash")
+	}
+	if !j.Steps[0].Done || j.Steps[1].Done {
+		t.Fatalf("steps done = %v, %v, want true, false", j.Steps[0].Done, j.Steps[1].Done)
+	}
+
+	if err = RecoverJournals(); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(resources, "app.asar"), "original")
+	assertNoFile(t, filepath.Join(resources, "_app.asar"))
+	assertNoFile(t, journalFile(resources))
+}
+
+func TestRecoverJournalsRollsBackUnstartedPatch(t *testing.T) {
+	resources := setupJournalTest(t)
+
+	// the journal was written, but not even the first rename happened
+	if _, err := BeginJournal("patch", resources, patchSteps(resources)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RecoverJournals(); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(resources, "app.asar"), "original")
+	assertNoFile(t, filepath.Join(resources, "_app.asar"))
+	assertNoFile(t, journalFile(resources))
+}
+
+func TestRecoverJournalsFinishesCompletedPatch(t *testing.T) {
+	resources := setupJournalTest(t)
+	backup := filepath.Join(resources, "app.asar.tmp")
+	writeTestFile(t, backup, "leftover")
+
+	j, err := BeginJournal("patch", resources, patchSteps(resources), backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// all steps done, but we died before cleaning up
+	if err = j.Run(func(file string) error {
+		writeTestFile(t, file, "patched")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = RecoverJournals(); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(resources, "app.asar"), "patched")
+	assertFile(t, filepath.Join(resources, "_app.asar"), "original")
+	assertNoFile(t, backup)
+	assertNoFile(t, journalFile(resources))
+}
+
+func TestStuckJournalCanBeDiscarded(t *testing.T) {
+	resources := setupJournalTest(t)
+
+	j, err := BeginJournal("patch", resources, patchSteps(resources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = j.Run(func(file string) error {
+		return errors.New("crash")
+	})
+	// the original app.asar vanished, so it can't be renamed back
+	if err = os.Remove(filepath.Join(resources, "_app.asar")); err != nil {
+		t.Fatal(err)
+	}
+
+	err = RecoverJournals()
+	if err == nil || !strings.Contains(err.Error(), journalFile(resources)) {
+		t.Fatalf("RecoverJournals() = %v, want an error naming the journal", err)
+	}
+	if !ExistsFile(journalFile(resources)) {
+		t.Fatal("the journal was deleted although rolling back failed")
+	}
+
+	_, err = BeginJournal("patch", resources, patchSteps(resources))
+	if !errors.Is(err, ErrJournalPending) || !strings.Contains(err.Error(), journalFile(resources)) {
+		t.Fatalf("BeginJournal() = %v, want ErrJournalPending naming the journal", err)
+	}
+
+	writeTestFile(t, filepath.Join(resources, "app.asar"), "fixed by hand")
+	if err = DiscardJournals(); err != nil {
+		t.Fatal(err)
+	}
+	assertNoFile(t, journalFile(resources))
+	assertFile(t, filepath.Join(resources, "app.asar"), "fixed by hand")
+
+	if _, err = BeginJournal("patch", resources, patchSteps(resources)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	path "path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InstallManifest describes the build in FilesDir. It is written to BaseDir/install.json whenever a build is
+// installed or rolled back to, and is what version checks and repairs go by
+type InstallManifest struct {
+	BuildInfo
+	Files            map[string]string `json:"files"` // file name -> sha256
+	InstalledAt      time.Time         `json:"installedAt"`
+	InstallerVersion string            `json:"installerVersion"`
+}
+
+// InstalledManifest is nil if Vencord isn't installed or was installed by an installer that didn't write one yet
+var InstalledManifest *InstallManifest
+
+func ManifestFile() string {
+	return path.Join(BaseDir, "install.json")
+}
+
+// ReadInstallManifest reads the manifest of the installed build. Returns nil and no error if there is none
+func ReadInstallManifest() (*InstallManifest, error) {
+	b, err := os.ReadFile(ManifestFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	m := &InstallManifest{}
+	if err = json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	if m.Hash == "" {
+		return nil, errors.New(ManifestFile() + " has no hash")
+	}
+	return m, nil
+}
+
+func writeInstallManifest(m *InstallManifest) error {
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(ManifestFile(), b, 0644); err != nil {
+		return err
+	}
+	_ = FixOwnership(ManifestFile())
+	return nil
+}
+
+// ChangedFiles returns the files in FilesDir that are missing or were modified since the install
+func (m *InstallManifest) ChangedFiles() []string {
+	var changed []string
+	for _, name := range SortedKeys(m.Files) {
+		if hash, _, err := hashFile(path.Join(FilesDir, name)); err != nil || hash != m.Files[name] {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// InstalledChannel returns the channel the installed build came from, or the current channel if unknown
+func InstalledChannel() string {
+	if InstalledManifest != nil {
+		if InstalledManifest.Channel == "" {
+			return "local"
+		}
+		return InstalledManifest.Channel
+	}
+	return CurrentChannel()
+}
+
+// IsUpToDate reports whether the latest build is installed and unmodified. During dry runs,
+// a build the plan already installs counts as installed
+func IsUpToDate() bool {
+	return currentReleaseState().IsUpToDate()
+}
+
+func (s releaseState) IsUpToDate() bool {
+	if build := plannedBuild(); build != "" {
+		return build == s.LatestHash
+	}
+	if s.LatestHash != s.InstalledHash {
+		return false
+	}
+	// installs by older installers have no manifest to check, so only the hash in their patcher.js counts
+	return s.InstalledManifest == nil || s.InstalledManifest.IsIntact()
+}
+
+var (
+	intactLock   sync.Mutex
+	intactFor    *InstallManifest
+	intactStamp  string
+	intactResult bool
+)
+
+// IsIntact reports whether FilesDir still contains exactly the files of m. Hashing them is slow and is asked for
+// before patching every install, so the result is reused until one of the files changes size or modification time
+func (m *InstallManifest) IsIntact() bool {
+	stamp := m.filesStamp()
+
+	intactLock.Lock()
+	defer intactLock.Unlock()
+	if intactFor != m || intactStamp != stamp {
+		intactFor, intactStamp = m, stamp
+		intactResult = len(m.ChangedFiles()) == 0
+	}
+	return intactResult
+}
+
+func (m *InstallManifest) filesStamp() string {
+	var sb strings.Builder
+	for _, name := range SortedKeys(m.Files) {
+		if info, err := os.Stat(path.Join(FilesDir, name)); err == nil {
+			fmt.Fprintf(&sb, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
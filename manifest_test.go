@@ -0,0 +1,75 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testManifest(t *testing.T) *InstallManifest {
+	writeTestFile(t, filepath.Join(FilesDir, "patcher.js"), "// Vencord abc")
+	hash, _, err := hashFile(filepath.Join(FilesDir, "patcher.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &InstallManifest{BuildInfo: BuildInfo{Hash: "abc"}, Files: map[string]string{"patcher.js": hash}}
+}
+
+func TestReleaseStateIsUpToDate(t *testing.T) {
+	setupTestDirs(t)
+	m := testManifest(t)
+	modified := testManifest(t)
+	modified.Files["renderer.js"] = "missing"
+
+	tests := []struct {
+		name  string
+		state releaseState
+		want  bool
+	}{
+		{"same version", releaseState{LatestHash: "abc", InstalledHash: "abc", InstalledManifest: m}, true},
+		{"outdated", releaseState{LatestHash: "def", InstalledHash: "abc", InstalledManifest: m}, false},
+		{"modified files", releaseState{LatestHash: "abc", InstalledHash: "abc", InstalledManifest: modified}, false},
+		{"no manifest", releaseState{LatestHash: "abc", InstalledHash: "abc"}, true},
+		{"no manifest, outdated", releaseState{LatestHash: "def", InstalledHash: "abc"}, false},
+		{"not installed", releaseState{LatestHash: "abc", InstalledHash: "None"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.IsUpToDate(); got != tt.want {
+				t.Errorf("IsUpToDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsIntactNoticesChanges(t *testing.T) {
+	setupTestDirs(t)
+	m := testManifest(t)
+	file := filepath.Join(FilesDir, "patcher.js")
+
+	if !m.IsIntact() {
+		t.Fatal("IsIntact() = false for an unmodified install")
+	}
+	writeTestFile(t, file, "// Vencord abd")
+	// same size, so make sure the modification time differs on coarse file systems
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if m.IsIntact() {
+		t.Error("IsIntact() = true after patcher.js was modified")
+	}
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if m.IsIntact() {
+		t.Error("IsIntact() = true after patcher.js was deleted")
+	}
+}
@@ -0,0 +1,304 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	path "path/filepath"
+	"strings"
+)
+
+// LocalBuildsSource is the folder or archive to install Vencord from. Empty to download it from GitHub
+var LocalBuildsSource string
+
+// Maximum size of a single file read from a local source, so a bogus archive can't exhaust memory
+const MaxLocalBuildSize = 64 << 20
+
+// SetLocalBuildsSource makes installLatestBuilds install from src. LatestHash is set to the version in its
+// patcher.js. An empty src switches back to downloading from GitHub. Takes stateLock, so not for the render thread
+func SetLocalBuildsSource(src string) error {
+	if src == "" {
+		stateLock.Lock()
+		LocalBuildsSource = ""
+		LatestHash = releaseHash(&ReleaseData)
+		stateLock.Unlock()
+		return nil
+	}
+
+	src, err := path.Abs(src)
+	if err != nil {
+		return err
+	}
+	files, err := readLocalBuilds(src)
+	if err != nil {
+		return err
+	}
+	hash := localBuildsHash(files)
+
+	stateLock.Lock()
+	LocalBuildsSource = src
+	LatestHash = hash
+	stateLock.Unlock()
+	Log.Debug("Using local builds from", src, "with hash", hash)
+	return nil
+}
+
+// localBuildsHash reads the version from the header of patcher.js like InitGithubDownloader does.
+// Builds without one are told apart by a hash of their files instead
+func localBuildsHash(files map[string][]byte) string {
+	if hash, ok := readPatcherHash(bytes.NewReader(files["patcher.js"])); ok {
+		return hash
+	}
+
+	h := sha256.New()
+	for _, name := range SliceFilter(SortedKeys(files), VencordAssets.Includes) {
+		fmt.Fprintf(h, "%s %d\n", name, len(files[name]))
+		h.Write(files[name])
+	}
+	hash := "local-" + hex.EncodeToString(h.Sum(nil))[:12]
+	Log.Warn("patcher.js has no version header, using", hash, "as its version")
+	return hash
+}
+
+// isLocalBuildFile reports whether name is one of the files we need from a local source
+func isLocalBuildFile(name string) bool {
+	return VencordAssets.Includes(strings.TrimSuffix(name, ".sig")) || SliceContains(ChecksumManifestNames, name)
+}
+
+// readLocalBuilds reads the Vencord files from the folder or archive src, keyed by file name
+func readLocalBuilds(src string) (files map[string][]byte, err error) {
+	lower := strings.ToLower(src)
+	switch {
+	case IsDirectory(src):
+		// accept both the dist folder and the folder containing it
+		if !ExistsFile(path.Join(src, "patcher.js")) && ExistsFile(path.Join(src, "dist", "patcher.js")) {
+			src = path.Join(src, "dist")
+		}
+		files, err = readBuildsDir(src)
+	case strings.HasSuffix(lower, ".zip"):
+		files, err = readBuildsZip(src)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		files, err = readBuildsTarGz(src)
+	default:
+		return nil, fmt.Errorf("%s is neither a folder nor a .zip or .tar.gz archive", src)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %w", src, err)
+	}
+
+	if missing := VencordAssets.MissingFiles(SortedKeys(files)); len(missing) != 0 {
+		return nil, fmt.Errorf("%s does not contain Vencord's %s", src, strings.Join(missing, ", "))
+	}
+	return files, nil
+}
+
+func readBuildsDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isLocalBuildFile(entry.Name()) {
+			continue
+		}
+		f, err := os.Open(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		err = addLocalBuildFile(files, entry.Name(), f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Archives are flattened, so it doesn't matter whether the files are at the root or in a dist folder
+func readBuildsZip(file string) (map[string][]byte, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer r.Close()
+
+	files := make(map[string][]byte)
+	for _, entry := range r.File {
+		name := path.Base(entry.Name)
+		if !entry.Mode().IsRegular() || !isLocalBuildFile(name) {
+			continue
+		}
+		f, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = addLocalBuildFile(files, name, f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func readBuildsTarGz(file string) (map[string][]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	r := tar.NewReader(gz)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Base(header.Name)
+		if header.Typeflag != tar.TypeReg || !isLocalBuildFile(name) {
+			continue
+		}
+		if err = addLocalBuildFile(files, name, r); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func addLocalBuildFile(files map[string][]byte, name string, r io.Reader) error {
+	if _, ok := files[name]; ok {
+		return fmt.Errorf("Found more than one %s", name)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r, MaxLocalBuildSize+1))
+	if err != nil {
+		return err
+	}
+	if len(b) > MaxLocalBuildSize {
+		return fmt.Errorf("%s is larger than %s", name, FormatBytes(MaxLocalBuildSize))
+	}
+
+	files[name] = b
+	return nil
+}
+
+// verifyLocalBuilds checks the assets against the checksum manifest and signatures that came with them.
+// Signatures are required if signing keys are pinned, just like for downloads
+func verifyLocalBuilds(files map[string][]byte) error {
+	var checksums Checksums
+	for _, name := range ChecksumManifestNames {
+		if b, ok := files[name]; ok {
+			checksums = parseChecksumManifest(b)
+			break
+		}
+	}
+	if checksums == nil {
+		Log.Debug("No checksum manifest found next to the local builds")
+	}
+
+	for _, name := range SortedKeys(files) {
+		if !VencordAssets.Includes(name) {
+			continue
+		}
+		b := files[name]
+
+		verified, err := checksums.Verify(name, b)
+		if err != nil {
+			return err
+		}
+
+		if VencordSigningKeys.Enabled() {
+			err := fmt.Errorf("%s: %w", name, ErrSignatureMissing)
+			if sig, ok := files[name+".sig"]; ok {
+				err = VencordSigningKeys.Verify(name, b, sig)
+			}
+			if err != nil {
+				return fmt.Errorf("Signature verification failed, refusing to install: %w", err)
+			}
+			verified = true
+		}
+
+		if err = checkVerified(name, verified); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installLocalBuilds installs the builds from the folder or archive src into FilesDir, going through a
+// staging dir like downloads do
+func installLocalBuilds(src string) error {
+	Log.Debug("Installing builds from", src)
+
+	files, err := readLocalBuilds(src)
+	if err != nil {
+		return err
+	}
+	hash := localBuildsHash(files)
+	assets := SliceFilter(SortedKeys(files), VencordAssets.Includes)
+	staging := StagingDir("local-" + hash)
+
+	if IsDryRun() {
+		planned(PlanWrite, path.Join(staging, "package.json"))
+		for _, name := range assets {
+			planned(PlanWrite, path.Join(staging, name))
+		}
+		return installBuild(staging, BuildInfo{Hash: hash, Source: src})
+	}
+
+	if err = verifyLocalBuilds(files); err != nil {
+		return err
+	}
+
+	cleanStagingDirs(staging)
+	// a previous attempt may have left files of a different build behind
+	_ = os.RemoveAll(staging)
+	if err = os.MkdirAll(staging, 0755); err != nil {
+		return fmt.Errorf("Failed to create staging dir: %w", err)
+	}
+
+	// see installLatestBuilds
+	if err = os.WriteFile(path.Join(staging, "package.json"), []byte("{}"), 0644); err != nil {
+		return fmt.Errorf("Failed to create package.json: %w", err)
+	}
+	for _, name := range assets {
+		if err = os.WriteFile(path.Join(staging, name), files[name], 0644); err != nil {
+			return fmt.Errorf("Failed to write %s: %w", name, err)
+		}
+	}
+
+	if err = installBuild(staging, BuildInfo{Hash: hash, Source: src}); err != nil {
+		return err
+	}
+
+	Log.Debug("Done!")
+	return nil
+}
@@ -0,0 +1,46 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocalBuildsHash(t *testing.T) {
+	build := func(patcher, renderer string, extra ...string) map[string][]byte {
+		files := map[string][]byte{"patcher.js": []byte(patcher), "renderer.js": []byte(renderer)}
+		for _, name := range extra {
+			files[name] = []byte("extra")
+		}
+		return files
+	}
+	unversioned := localBuildsHash(build("patcher", "renderer"))
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		want  func(hash string) bool
+	}{
+		{"version header", build("// Vencord 1a2b3c4\npatcher", "renderer"), func(hash string) bool { return hash == "1a2b3c4" }},
+		{"no header", build("patcher", "renderer"), func(hash string) bool {
+			return strings.HasPrefix(hash, "local-") && hash != "Unknown"
+		}},
+		{"same files", build("patcher", "renderer"), func(hash string) bool { return hash == unversioned }},
+		{"ignores signatures", build("patcher", "renderer", "patcher.js.sig", "sha256sums.txt"), func(hash string) bool { return hash == unversioned }},
+		{"different patcher", build("patcher2", "renderer"), func(hash string) bool { return hash != unversioned }},
+		{"different renderer", build("patcher", "renderer2"), func(hash string) bool { return hash != unversioned }},
+		{"content moved between files", build("patcherr", "enderer"), func(hash string) bool { return hash != unversioned }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hash := localBuildsHash(tt.files); !tt.want(hash) {
+				t.Errorf("localBuildsHash() = %s (unversioned build is %s)", hash, unversioned)
+			}
+		})
+	}
+}
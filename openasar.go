@@ -8,12 +8,12 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
-	"net/http"
 	"os"
 	path "path/filepath"
-	"strconv"
 )
 
 const OpenAsarDownloadLink = "https://github.com/GooseMod/OpenAsar/releases/download/nightly/app.asar"
@@ -63,7 +63,13 @@ func (di *DiscordInstall) IsOpenAsar() (retBool bool) {
 	return false
 }
 
-func (di *DiscordInstall) InstallOpenAsar() error {
+func (di *DiscordInstall) setOpenAsar(isOpenAsar *bool) {
+	stateLock.Lock()
+	di.isOpenAsar = isOpenAsar
+	stateLock.Unlock()
+}
+
+func (di *DiscordInstall) InstallOpenAsar(ctx context.Context) error {
 	PreparePatch(di)
 
 	dir := path.Join(di.appPath, "..")
@@ -73,27 +79,40 @@ func (di *DiscordInstall) InstallOpenAsar() error {
 	}
 	_ = asarFile.Close()
 
-	if err = os.Rename(asarFile.Name(), path.Join(dir, "app.asar.backup")); err != nil {
-		return err
+	if _, err = BackupOriginalAsar(di, "install-openasar"); err != nil {
+		Log.Warn("Failed to back up the original app.asar:", err)
 	}
 
-	res, err := http.Get(OpenAsarDownloadLink)
-	if err != nil {
-		return err
-	} else if res.StatusCode >= 300 {
-		return errors.New("Failed to fetch OpenAsar - " + strconv.Itoa(res.StatusCode) + ": " + res.Status)
+	if len(Settings.OpenAsarUrls) == 0 {
+		return fmt.Errorf("Failed to fetch OpenAsar: %w", ErrNoUrls)
+	}
+
+	if IsDryRun() {
+		planned(PlanRename, asarFile.Name(), path.Join(dir, "app.asar.backup"))
+		planned(PlanDownload, Settings.OpenAsarUrls[0], asarFile.Name())
+		di.setOpenAsar(Ptr(true))
+		return nil
 	}
 
-	outFile, err := os.Create(asarFile.Name())
+	// Download first so a failed download doesn't leave the install without app.asar
+	b, err := downloadBytesFromMirrors(ctx, Settings.OpenAsarUrls)
 	if err != nil {
+		return fmt.Errorf("Failed to fetch OpenAsar: %w", err)
+	}
+
+	backup := path.Join(dir, "app.asar.backup")
+	if err = os.Rename(asarFile.Name(), backup); err != nil {
 		return err
 	}
 
-	if _, err = io.Copy(outFile, res.Body); err != nil {
+	if err = os.WriteFile(asarFile.Name(), b, 0644); err != nil {
+		if innerErr := os.Rename(backup, asarFile.Name()); innerErr != nil {
+			Log.Error("Failed to restore", asarFile.Name(), "from", backup+":", innerErr)
+		}
 		return err
 	}
 
-	di.isOpenAsar = Ptr(true)
+	di.setOpenAsar(Ptr(true))
 	return nil
 }
 
@@ -114,11 +133,13 @@ func (di *DiscordInstall) UninstallOpenAsar() error {
 		}
 		_ = asarFile.Close()
 
-		if err = os.Rename(file, asarFile.Name()); err != nil {
-			return err
+		if !planned(PlanRename, file, asarFile.Name()) {
+			if err = os.Rename(file, asarFile.Name()); err != nil {
+				return err
+			}
 		}
 
-		di.isOpenAsar = Ptr(false)
+		di.setOpenAsar(Ptr(false))
 		return nil
 	}
 
@@ -47,69 +47,122 @@ type DiscordInstall struct {
 	path             string // the base path
 	branch           string // canary / stable / ...
 	appPath          string // List of app folder to patch
-	isPatched        bool
+	patchState       PatchState
+	shim             *ShimMetadata // metadata of our app.asar, nil if not patched by us
 	isFlatpak        bool
 	isSystemElectron bool // Needs special care https://aur.archlinux.org/packages/discord_arch_electron
 	isOpenAsar       *bool
 }
 
+func (di *DiscordInstall) updatePatchState() {
+	di.patchState, di.shim = DetectPatchState(di.resourcesDir())
+}
+
+// isPatched reports whether di is patched by us, even if its shim loads an outdated patcher
+func (di *DiscordInstall) isPatched() bool {
+	return di.patchState == PatchStateVencord || di.patchState == PatchStateStalePatcher
+}
+
+// setPatched updates the patch state of di after patching or unpatching it. Dry runs don't change
+// anything on disk, so there it is only assumed
+func (di *DiscordInstall) setPatched(isPatched bool) {
+	state, shim := DetectPatchState(di.resourcesDir())
+	if IsDryRun() {
+		state, shim = Ternary(isPatched, PatchStateVencord, PatchStateUnpatched), di.shim
+	}
+	stateLock.Lock()
+	di.patchState, di.shim = state, shim
+	stateLock.Unlock()
+}
+
+// patchLabel returns a suffix describing the patch state for install lists
+func (di *DiscordInstall) patchLabel() string {
+	switch di.patchState {
+	case PatchStateVencord:
+		return " [PATCHED]"
+	case PatchStateStalePatcher:
+		return " [PATCHED - NEEDS REPAIR]"
+	case PatchStateForeign:
+		return " [PATCHED BY ANOTHER MOD]"
+	default:
+		return ""
+	}
+}
+
+// resourcesDir returns the folder containing app.asar
+func (di *DiscordInstall) resourcesDir() string {
+	if di.isSystemElectron {
+		return di.path
+	}
+	return path.Join(di.appPath, "..")
+}
+
 //region Patch
 
-func patchAppAsar(dir string, isSystemElectron bool) (err error) {
+func patchAppAsar(dir string, isSystemElectron bool) error {
 	appAsar := path.Join(dir, "app.asar")
 	_appAsar := path.Join(dir, "_app.asar")
 
-	var renamesDone [][]string
-	defer func() {
-		if err != nil && len(renamesDone) > 0 {
-			Log.Error("Failed to patch. Undoing partial patch")
-			for _, rename := range renamesDone {
-				if innerErr := os.Rename(rename[1], rename[0]); innerErr != nil {
-					Log.Error("Failed to undo partial patch. This install is probably bricked.", innerErr)
-				} else {
-					Log.Info("Successfully undid all changes")
-				}
-			}
-		}
-	}()
+	steps := []JournalStep{RenameStep(appAsar, _appAsar)}
+	if isSystemElectron {
+		steps = append(steps, RenameStep(appAsar+".unpacked", _appAsar+".unpacked"))
+	}
+	steps = append(steps, CreateStep(appAsar))
 
-	Log.Debug("Renaming", appAsar, "to", _appAsar)
-	if err := os.Rename(appAsar, _appAsar); err != nil {
-		err = CheckIfErrIsCauseItsBusyRn(err)
-		Log.Error(err.Error())
+	journal, err := BeginJournal("patch", dir, steps)
+	if err != nil {
 		return err
 	}
-	renamesDone = append(renamesDone, []string{appAsar, _appAsar})
 
-	if isSystemElectron {
-		from, to := appAsar+".unpacked", _appAsar+".unpacked"
-		Log.Debug("Renaming", from, "to", to)
-		err := os.Rename(from, to)
-		if err != nil {
-			return err
+	err = journal.Run(func(file string) error {
+		Log.Debug("Writing custom app.asar to", file)
+		return WriteAppAsar(file, Patcher)
+	})
+	if err != nil {
+		Log.Error("Failed to patch. Undoing partial patch")
+		if innerErr := journal.Rollback(); innerErr != nil {
+			Log.Error("Failed to undo partial patch. This install is probably bricked. Restore a backup with --restore-backup", innerErr)
+		} else {
+			Log.Info("Successfully undid all changes")
 		}
-		renamesDone = append(renamesDone, []string{from, to})
-	}
-
-	Log.Debug("Writing custom app.asar to", appAsar)
-	if err := WriteAppAsar(appAsar, Patcher); err != nil {
 		return err
 	}
 
+	journal.Commit()
 	return nil
 }
 
+// ErrAlreadyReported is matched by errors that were already shown to the user, so they aren't shown twice
+var ErrAlreadyReported = errors.New("already reported")
+
+type reportedError struct {
+	err error
+}
+
+func (e *reportedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reportedError) Unwrap() []error {
+	return []error{ErrAlreadyReported, e.err}
+}
+
 func (di *DiscordInstall) patch() error {
 	Log.Info("Patching " + di.path + "...")
-	if LatestHash != InstalledHash {
+	if !snapshotReleaseState().IsUpToDate() {
 		if err := InstallLatestBuilds(); err != nil {
-			return nil // already shown dialog so don't return same error again
+			return &reportedError{err}
 		}
 	}
 
 	PreparePatch(di)
 
-	if di.isPatched {
+	if _, err := BackupOriginalAsar(di, "patch"); err != nil {
+		Log.Warn("Failed to back up the original app.asar:", err)
+	}
+
+	// also undo other mods, or patching would replace the original _app.asar with theirs
+	if di.patchState != PatchStateUnpatched {
 		Log.Info(di.path, "is already patched. Unpatching first...")
 		if err := di.unpatch(); err != nil {
 			if errors.Is(err, os.ErrPermission) {
@@ -119,116 +172,93 @@ func (di *DiscordInstall) patch() error {
 		}
 	}
 
-	if di.isSystemElectron {
-		if err := patchAppAsar(di.path, true); err != nil {
-			return err
-		}
-	} else {
-		if err := patchAppAsar(path.Join(di.appPath, ".."), false); err != nil {
-			return err
-		}
+	if err := patchAppAsar(di.resourcesDir(), di.isSystemElectron); err != nil {
+		return err
 	}
 
-	Log.Info("Successfully patched", di.path)
-	di.isPatched = true
+	Log.Info(Ternary(IsDryRun(), "Planned patch of", "Successfully patched"), di.path)
+	di.setPatched(true)
 
 	if di.isFlatpak {
-		pathElements := strings.Split(di.path, "/")
-		var name string
-		for _, e := range pathElements {
-			if strings.HasPrefix(e, "com.discordapp") {
-				name = e
-				break
-			}
-		}
-
 		Log.Debug("This is a flatpak. Trying to grant the Flatpak access to", FilesDir+"...")
 
-		isSystemFlatpak := strings.HasPrefix(di.path, "/var")
-		var args []string
-		if !isSystemFlatpak {
-			args = append(args, "--user")
-		}
-		args = append(args, "override", name, "--filesystem="+FilesDir)
-		fullCmd := "flatpak " + strings.Join(args, " ")
-
-		Log.Debug("Running", fullCmd)
-
-		var err error
-		if !isSystemFlatpak && os.Getuid() == 0 {
-			// We are operating on a user flatpak but are root
-			actualUser := os.Getenv("SUDO_USER")
-			Log.Debug("This is a user install but we are root. Using su to run as", actualUser)
-			cmd := exec.Command("su", "-", actualUser, "-c", "sh", "-c", fullCmd)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			err = cmd.Run()
-		} else {
-			cmd := exec.Command("flatpak", args...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			err = cmd.Run()
-		}
-		if err != nil {
+		cmd := di.flatpakCommand("override", di.flatpakId(), "--filesystem="+FilesDir)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := runCommand(cmd); err != nil {
 			return errors.New("Failed to grant Discord Flatpak access to " + FilesDir + ": " + err.Error())
 		}
 	}
 	return nil
 }
 
+// flatpakId returns the Flatpak application id (com.discordapp.*) of di
+func (di *DiscordInstall) flatpakId() string {
+	for _, e := range strings.Split(di.path, "/") {
+		if strings.HasPrefix(e, "com.discordapp") {
+			return e
+		}
+	}
+	return ""
+}
+
+func (di *DiscordInstall) isSystemFlatpak() bool {
+	return strings.HasPrefix(di.path, "/var")
+}
+
+// flatpakCommand builds a flatpak command operating on the installation (user or system) di belongs to
+func (di *DiscordInstall) flatpakCommand(args ...string) *exec.Cmd {
+	isSystemFlatpak := di.isSystemFlatpak()
+	if !isSystemFlatpak {
+		args = Prepend(args, "--user")
+	}
+	fullCmd := "flatpak " + strings.Join(args, " ")
+
+	Log.Debug("Running", fullCmd)
+
+	if !isSystemFlatpak && os.Getuid() == 0 {
+		// We are operating on a user flatpak but are root
+		actualUser := os.Getenv("SUDO_USER")
+		Log.Debug("This is a user install but we are root. Using su to run as", actualUser)
+		return exec.Command("su", "-", actualUser, "-c", "sh", "-c", fullCmd)
+	}
+	return exec.Command("flatpak", args...)
+}
+
 //endregion
 
 // region Unpatch
 
-func unpatchAppAsar(dir string, isSystemElectron bool) (errOut error) {
+func unpatchAppAsar(dir string, isSystemElectron bool) error {
 	appAsar := path.Join(dir, "app.asar")
 	appAsarTmp := path.Join(dir, "app.asar.tmp")
 	_appAsar := path.Join(dir, "_app.asar")
 
-	var renamesDone [][]string
-	defer func() {
-		if errOut != nil && len(renamesDone) > 0 {
-			Log.Error("Failed to unpatch. Undoing partial unpatch")
-			for _, rename := range renamesDone {
-				if innerErr := os.Rename(rename[1], rename[0]); innerErr != nil {
-					Log.Error("Failed to undo partial unpatch. This install is probably bricked.", innerErr)
-				} else {
-					Log.Info("Successfully undid all changes")
-				}
-			}
-		} else if errOut == nil {
-			if innerErr := os.RemoveAll(appAsarTmp); innerErr != nil {
-				Log.Warn("Failed to delete temporary app.asar (patch folder) backup. This is whatever but you might want to delete it manually.", innerErr)
-			}
-		}
-	}()
-
-	Log.Debug("Deleting", appAsar)
-	if err := os.Rename(appAsar, appAsarTmp); err != nil {
-		err = CheckIfErrIsCauseItsBusyRn(err)
-		Log.Error(err.Error())
-		errOut = err
-	} else {
-		renamesDone = append(renamesDone, []string{appAsar, appAsarTmp})
+	steps := []JournalStep{
+		RenameStep(appAsar, appAsarTmp),
+		RenameStep(_appAsar, appAsar),
+	}
+	if isSystemElectron {
+		steps = append(steps, RenameStep(_appAsar+".unpacked", appAsar+".unpacked"))
 	}
 
-	Log.Debug("Renaming", _appAsar, "to", appAsar)
-	if err := os.Rename(_appAsar, appAsar); err != nil {
-		err = CheckIfErrIsCauseItsBusyRn(err)
-		Log.Error(err.Error())
-		errOut = err
-	} else {
-		renamesDone = append(renamesDone, []string{_appAsar, appAsar})
+	journal, err := BeginJournal("unpatch", dir, steps, appAsarTmp)
+	if err != nil {
+		return err
 	}
 
-	if isSystemElectron {
-		Log.Debug("Renaming", _appAsar+".unpacked", "to", appAsar+".unpacked")
-		if err := os.Rename(_appAsar+".unpacked", appAsar+".unpacked"); err != nil {
-			Log.Error(err.Error())
-			errOut = err
+	if err = journal.Run(nil); err != nil {
+		Log.Error("Failed to unpatch. Undoing partial unpatch")
+		if innerErr := journal.Rollback(); innerErr != nil {
+			Log.Error("Failed to undo partial unpatch. This install is probably bricked. Restore a backup with --restore-backup", innerErr)
+		} else {
+			Log.Info("Successfully undid all changes")
 		}
+		return err
 	}
-	return
+
+	journal.Commit()
+	return nil
 }
 
 func (di *DiscordInstall) unpatch() error {
@@ -236,18 +266,12 @@ func (di *DiscordInstall) unpatch() error {
 
 	PreparePatch(di)
 
-	if di.isSystemElectron {
-		if err := unpatchAppAsar(di.path, true); err != nil {
-			return err
-		}
-	} else {
-		if err := unpatchAppAsar(path.Join(di.appPath, ".."), false); err != nil {
-			return err
-		}
+	if err := unpatchAppAsar(di.resourcesDir(), di.isSystemElectron); err != nil {
+		return err
 	}
 
-	Log.Info("Successfully unpatched", di.path)
-	di.isPatched = false
+	Log.Info(Ternary(IsDryRun(), "Planned unpatch of", "Successfully unpatched"), di.path)
+	di.setPatched(false)
 	return nil
 }
 
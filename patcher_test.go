@@ -0,0 +1,59 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsPatched(t *testing.T) {
+	setupTestDirs(t)
+	writeTestFile(t, Patcher, "// Vencord abc")
+
+	tests := []struct {
+		name    string
+		setup   func(resources string)
+		want    PatchState
+		patched bool
+	}{
+		{"unpatched", func(resources string) {
+			writeTestFile(t, filepath.Join(resources, "app.asar"), "discord")
+		}, PatchStateUnpatched, false},
+		{"patched", func(resources string) {
+			writeTestFile(t, filepath.Join(resources, "_app.asar"), "discord")
+			if err := WriteAppAsar(filepath.Join(resources, "app.asar"), Patcher); err != nil {
+				t.Fatal(err)
+			}
+		}, PatchStateVencord, true},
+		{"patched by an older install", func(resources string) {
+			writeTestFile(t, filepath.Join(resources, "_app.asar"), "discord")
+			if err := WriteAppAsar(filepath.Join(resources, "app.asar"), filepath.Join(t.TempDir(), "patcher.js")); err != nil {
+				t.Fatal(err)
+			}
+		}, PatchStateStalePatcher, true},
+		{"patched by another mod", func(resources string) {
+			writeTestFile(t, filepath.Join(resources, "_app.asar"), "discord")
+			writeTestFile(t, filepath.Join(resources, "app.asar"), "another mod")
+		}, PatchStateForeign, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := t.TempDir()
+			tt.setup(resources)
+
+			di := &DiscordInstall{path: resources, appPath: filepath.Join(resources, "app")}
+			di.updatePatchState()
+			if di.patchState != tt.want {
+				t.Errorf("patchState = %v, want %v", di.patchState, tt.want)
+			}
+			if di.isPatched() != tt.patched {
+				t.Errorf("isPatched() = %v, want %v", di.isPatched(), tt.patched)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// During dry runs, planned() records operations that touch disk or network instead of running them
+
+const (
+	PlanRename   = "rename"
+	PlanWrite    = "write"
+	PlanRemove   = "remove"
+	PlanDownload = "download"
+	PlanCommand  = "run"
+	PlanKill     = "kill"
+)
+
+type PlannedOperation struct {
+	Kind   string
+	Target string
+}
+
+type Plan struct {
+	lock       sync.Mutex // downloads are planned from several goroutines
+	operations []PlannedOperation
+	build      string // hash of the build the plan installs, so it isn't planned again
+}
+
+// activePlan is swapped by gui tasks while the render thread checks IsDryRun, so it is atomic
+var activePlan atomic.Pointer[Plan]
+
+func IsDryRun() bool {
+	return activePlan.Load() != nil
+}
+
+// StartDryRun makes all following operations only be planned. Used by the cli, which exits afterwards
+func StartDryRun() *Plan {
+	plan := &Plan{}
+	activePlan.Store(plan)
+	return plan
+}
+
+// PlanOperations runs fn in dry-run mode and returns the operations it would have performed.
+// Installs are updated as if the operations ran, so fn should operate on copies of them
+func PlanOperations(fn func() error) (*Plan, error) {
+	plan := &Plan{}
+	if !activePlan.CompareAndSwap(nil, plan) {
+		return nil, errors.New("Another operation is already being planned")
+	}
+	defer activePlan.Store(nil)
+
+	err := fn()
+	return plan, err
+}
+
+// planned records the operation if this is a dry run. Returns whether the caller must skip it
+func planned(kind string, target ...string) bool {
+	plan := activePlan.Load()
+	if plan == nil {
+		return false
+	}
+	plan.lock.Lock()
+	plan.operations = append(plan.operations, PlannedOperation{kind, strings.Join(target, " -> ")})
+	plan.lock.Unlock()
+	return true
+}
+
+// planBuild remembers that the plan installs the build with the given hash
+func planBuild(hash string) {
+	if plan := activePlan.Load(); plan != nil {
+		plan.lock.Lock()
+		plan.build = hash
+		plan.lock.Unlock()
+	}
+}
+
+// plannedBuild returns the hash of the build the active plan installs, if any
+func plannedBuild() string {
+	plan := activePlan.Load()
+	if plan == nil {
+		return ""
+	}
+	plan.lock.Lock()
+	defer plan.lock.Unlock()
+	return plan.build
+}
+
+func (p *Plan) Operations() []PlannedOperation {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]PlannedOperation(nil), p.operations...)
+}
+
+// runCommand runs cmd, or only plans it during dry runs
+func runCommand(cmd *exec.Cmd) error {
+	if planned(PlanCommand, strings.Join(cmd.Args, " ")) {
+		return nil
+	}
+	return cmd.Run()
+}
+
+func (p *Plan) String() string {
+	ops := p.Operations()
+	if len(ops) == 0 {
+		return "Nothing to do"
+	}
+
+	var sb strings.Builder
+	for i, op := range ops {
+		sb.WriteString(fmt.Sprintf("%2d. %-8s %s\n", i+1, op.Kind, op.Target))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,63 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestPlanOperationsFromSeveralGoroutines(t *testing.T) {
+	plan, err := PlanOperations(func() error {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				planned(PlanDownload, "asset"+strconv.Itoa(i))
+			}(i)
+		}
+		wg.Wait()
+
+		if _, err := PlanOperations(func() error { return nil }); err == nil {
+			t.Error("PlanOperations succeeded while another plan was active")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsDryRun() {
+		t.Error("IsDryRun() = true after PlanOperations returned")
+	}
+	if n := len(plan.Operations()); n != 20 {
+		t.Errorf("planned %d operations, want 20", n)
+	}
+}
+
+func TestDryRunDoesNotRecoverDist(t *testing.T) {
+	setupTestDirs(t)
+	old := FilesDir + ".old"
+	writeTestFile(t, filepath.Join(old, "patcher.js"), "old patcher")
+
+	plan, err := PlanOperations(func() error {
+		recoverDist()
+		writeCachedRelease(&cachedRelease{Url: "https://example.com/releases/latest"})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(old, "patcher.js"), "old patcher")
+	assertNoFile(t, filepath.Join(FilesDir, "patcher.js"))
+	assertNoFile(t, ReleaseCacheDir())
+	if n := len(plan.Operations()); n != 1 {
+		t.Errorf("planned %d operations, want the rename of %s", n, old)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	path "path/filepath"
+	"time"
+)
+
+// Release json is cached with its ETag, and used if no release url can be reached
+
+type cachedRelease struct {
+	Url       string          `json:"url"`
+	ETag      string          `json:"etag"`
+	CheckedAt time.Time       `json:"checkedAt"`
+	Body      json.RawMessage `json:"body"`
+}
+
+func ReleaseCacheDir() string {
+	return path.Join(BaseDir, "cache")
+}
+
+func releaseCacheFile(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return path.Join(ReleaseCacheDir(), "release-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// readCachedRelease returns the cached release of url, or nil if there is none
+func readCachedRelease(url string) *cachedRelease {
+	b, err := os.ReadFile(releaseCacheFile(url))
+	if err != nil {
+		return nil
+	}
+
+	c := &cachedRelease{}
+	if err = json.Unmarshal(b, c); err != nil || c.Url != url {
+		Log.Debug("Ignoring broken release cache of", url)
+		return nil
+	}
+	return c
+}
+
+func writeCachedRelease(c *cachedRelease) {
+	if IsDryRun() {
+		return
+	}
+	b, err := json.Marshal(c)
+	if err == nil {
+		err = os.MkdirAll(ReleaseCacheDir(), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(releaseCacheFile(c.Url), b, 0644)
+	}
+	if err != nil {
+		Log.Warn("Failed to cache release of", c.Url+":", err)
+		return
+	}
+	_ = FixOwnership(ReleaseCacheDir())
+}
+
+// cachedGithubRelease returns the newest cached release of any of urls
+func cachedGithubRelease(urls []string) (*GithubRelease, bool) {
+	var newest *cachedRelease
+	for _, url := range urls {
+		if c := readCachedRelease(url); c != nil && (newest == nil || c.CheckedAt.After(newest.CheckedAt)) {
+			newest = c
+		}
+	}
+	if newest == nil {
+		return nil, false
+	}
+
+	release, err := parseGithubRelease(newest.Url, newest.Body)
+	if err != nil {
+		return nil, false
+	}
+	release.checkedAt = newest.CheckedAt
+	return release, true
+}
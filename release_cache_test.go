@@ -0,0 +1,187 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseGithubRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantTag string
+		wantErr bool
+	}{
+		{"single release", `{"name": "Vencord abcdef1", "tag_name": "devbuild", "assets": [{"name": "patcher.js"}]}`, "devbuild", false},
+		{"list", `[{"tag_name": "v1.2.0"}, {"tag_name": "v1.1.0"}]`, "v1.2.0", false},
+		{"list with whitespace", "\n  [{\"tag_name\": \"v1.2.0\"}]", "v1.2.0", false},
+		{"list skips drafts", `[{"tag_name": "v1.3.0", "draft": true}, {"tag_name": "v1.2.0"}]`, "v1.2.0", false},
+		{"only drafts", `[{"tag_name": "v1.3.0", "draft": true}]`, "", true},
+		{"empty list", `[]`, "", true},
+		{"broken release", `{"tag_name": 1}`, "", true},
+		{"broken list", `[{"tag_name": 1}]`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release, err := parseGithubRelease("https://example.com/releases", json.RawMessage(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGithubRelease() = %v, %v, want error %v", release, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if release.TagName != tt.wantTag {
+				t.Errorf("TagName = %s, want %s", release.TagName, tt.wantTag)
+			}
+			if release.source != "https://example.com/releases" {
+				t.Errorf("source = %s, want the url", release.source)
+			}
+		})
+	}
+}
+
+func TestReadCachedRelease(t *testing.T) {
+	const url = "https://example.com/releases/latest"
+
+	tests := []struct {
+		name  string
+		cache func()
+		want  bool
+	}{
+		{"missing", func() {}, false},
+		{"written", func() {
+			writeCachedRelease(&cachedRelease{Url: url, ETag: `"v1"`, Body: json.RawMessage(`{}`)})
+		}, true},
+		{"broken", func() {
+			writeTestFile(t, releaseCacheFile(url), "{")
+		}, false},
+		{"other url", func() {
+			b, _ := json.Marshal(&cachedRelease{Url: "https://example.com/other", Body: json.RawMessage(`{}`)})
+			writeTestFile(t, releaseCacheFile(url), string(b))
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDirs(t)
+			tt.cache()
+			if c := readCachedRelease(url); (c != nil) != tt.want {
+				t.Errorf("readCachedRelease() = %v, want a cached release %v", c, tt.want)
+			}
+		})
+	}
+}
+
+// releaseServer serves release, using etag for conditional requests. If status is set, it is returned instead
+type releaseServer struct {
+	release     string
+	etag        string
+	status      int
+	ifNoneMatch []string
+}
+
+func (s *releaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.ifNoneMatch = append(s.ifNoneMatch, r.Header.Get("If-None-Match"))
+	switch {
+	case s.status != 0:
+		w.WriteHeader(s.status)
+	case s.etag != "" && r.Header.Get("If-None-Match") == s.etag:
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		w.Header().Set("ETag", s.etag)
+		_, _ = w.Write([]byte(s.release))
+	}
+}
+
+func TestFetchGithubReleaseETag(t *testing.T) {
+	setupTestDirs(t)
+	s := &releaseServer{release: `{"tag_name": "devbuild", "name": "Vencord aaaaaaa"}`, etag: `"v1"`}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	url := srv.URL + "/releases/latest"
+
+	steps := []struct {
+		name            string
+		update          func()
+		wantIfNoneMatch string
+		wantName        string
+	}{
+		{"first fetch", func() {}, "", "Vencord aaaaaaa"},
+		{"not modified", func() {}, `"v1"`, "Vencord aaaaaaa"},
+		{"modified", func() {
+			s.release, s.etag = `{"tag_name": "devbuild", "name": "Vencord bbbbbbb"}`, `"v2"`
+		}, `"v1"`, "Vencord bbbbbbb"},
+		{"not modified again", func() {}, `"v2"`, "Vencord bbbbbbb"},
+	}
+	for i, step := range steps {
+		step.update()
+		before := time.Now().UTC()
+
+		release, err := fetchGithubRelease(url)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if release.Name != step.wantName {
+			t.Errorf("%s: Name = %s, want %s", step.name, release.Name, step.wantName)
+		}
+		if s.ifNoneMatch[i] != step.wantIfNoneMatch {
+			t.Errorf("%s: sent If-None-Match %q, want %q", step.name, s.ifNoneMatch[i], step.wantIfNoneMatch)
+		}
+		if release.IsCached() {
+			t.Errorf("%s: release counts as cached, but was fetched", step.name)
+		}
+		if c := readCachedRelease(url); c == nil || c.CheckedAt.Before(before.Add(-time.Second)) {
+			t.Errorf("%s: cache = %v, want it checked just now", step.name, c)
+		}
+	}
+}
+
+func TestGetGithubReleaseFallsBackToCache(t *testing.T) {
+	setupTestDirs(t)
+	older := httptest.NewServer(&releaseServer{release: `{"tag_name": "devbuild", "name": "Vencord aaaaaaa"}`})
+	defer older.Close()
+	newer := httptest.NewServer(&releaseServer{release: `{"tag_name": "devbuild", "name": "Vencord bbbbbbb"}`})
+	defer newer.Close()
+	urls := []string{older.URL, newer.URL}
+
+	if _, err := GetGithubRelease(urls[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetGithubRelease(urls[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := httptest.NewServer(&releaseServer{status: http.StatusBadGateway})
+	defer failing.Close()
+	// the next url is tried before falling back to the cache
+	release, err := GetGithubRelease([]string{failing.URL, newer.URL})
+	if err != nil || release.Name != "Vencord bbbbbbb" || release.IsCached() {
+		t.Fatalf("GetGithubRelease() = %v, %v, want the fetched release", release, err)
+	}
+
+	older.Close()
+	newer.Close()
+	release, err = GetGithubRelease(urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Name != "Vencord bbbbbbb" || !release.IsCached() || release.fetchErr == nil {
+		t.Errorf("GetGithubRelease() = %s, cached %v, err %v, want the newest cached release", release.Name, release.IsCached(), release.fetchErr)
+	}
+
+	if err = os.RemoveAll(ReleaseCacheDir()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetGithubRelease(urls); err == nil {
+		t.Error("GetGithubRelease() succeeded without a cache")
+	}
+}
@@ -7,10 +7,9 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path"
 	"runtime"
@@ -20,8 +19,10 @@ import (
 
 var IsSelfOutdated = false
 var SelfUpdateCheckDoneChan = make(chan bool, 1)
+var InstallerRelease *GithubRelease
 
-func init() {
+// InitSelfUpdater checks for installer updates in the background. Must be called after LoadConfig
+func InitSelfUpdater() {
 	//goland:noinspection GoBoolExpressions
 	if buildinfo.InstallerTag == buildinfo.VersionUnknown {
 		Log.Debug("Disabling self updater as this is not a release build")
@@ -33,11 +34,12 @@ func init() {
 	go func() {
 		Log.Debug("Checking for Installer Updates...")
 
-		res, err := GetGithubRelease(InstallerReleaseUrl, InstallerReleaseUrlFallback)
+		res, err := GetGithubRelease(Settings.InstallerReleaseUrls)
 		if err != nil {
 			Log.Warn("Failed to check for self updates:", err)
 			SelfUpdateCheckDoneChan <- false
 		} else {
+			InstallerRelease = res
 			IsSelfOutdated = res.TagName != buildinfo.InstallerTag
 			Log.Debug("Is self outdated?", IsSelfOutdated)
 			SelfUpdateCheckDoneChan <- true
@@ -47,17 +49,25 @@ func init() {
 
 func GetInstallerDownloadLink() string {
 	const BaseUrl = "https://github.com/Vencord/Installer/releases/latest/download/"
+	var filename string
 	switch runtime.GOOS {
 	case "windows":
-		filename := Ternary(buildinfo.UiType == buildinfo.UiTypeCli, "VencordInstallerCli.exe", "VencordInstaller.exe")
-		return BaseUrl + filename
+		filename = Ternary(buildinfo.UiType == buildinfo.UiTypeCli, "VencordInstallerCli.exe", "VencordInstaller.exe")
 	case "darwin":
-		return BaseUrl + "VencordInstaller.MacOS.zip"
+		filename = "VencordInstaller.MacOS.zip"
 	case "linux":
-		return BaseUrl + "VencordInstallerCli-linux"
+		filename = "VencordInstallerCli-linux"
 	default:
 		return ""
 	}
+
+	// prefer the link from the release we fetched, which points at the mirror if one is configured
+	if InstallerRelease != nil {
+		if i := SliceIndexFunc(InstallerRelease.Assets, func(ass GithubAsset) bool { return ass.Name == filename }); i != -1 {
+			return InstallerRelease.Assets[i].DownloadURL
+		}
+	}
+	return BaseUrl + filename
 }
 
 func CanUpdateSelf() bool {
@@ -84,11 +94,20 @@ func UpdateSelf() error {
 
 	ownExeDir := path.Dir(ownExePath)
 
-	res, err := http.Get(url)
+	b, err := downloadBytes(context.Background(), url)
 	if err != nil {
-		return err
+		return fmt.Errorf("Failed to download update: %w", err)
+	}
+
+	if InstallerSigningKeys.Enabled() {
+		sig, err := downloadBytes(context.Background(), url+".sig")
+		if err != nil {
+			return fmt.Errorf("Failed to download signature of update: %w", err)
+		}
+		if err = InstallerSigningKeys.Verify(path.Base(url), b, sig); err != nil {
+			return fmt.Errorf("Signature verification failed, refusing to update: %w", err)
+		}
 	}
-	defer res.Body.Close()
 
 	tmp, err := os.CreateTemp(ownExeDir, "VencordInstallerUpdate")
 	if err != nil {
@@ -99,10 +118,10 @@ func UpdateSelf() error {
 		_ = os.Remove(tmp.Name())
 	}()
 	if err = tmp.Chmod(0o755); err != nil {
-		return fmt.Errorf("Failed to chmod 755", tmp.Name()+":", err)
+		return fmt.Errorf("Failed to chmod 755 %s: %w", tmp.Name(), err)
 	}
 
-	if _, err = io.Copy(tmp, res.Body); err != nil {
+	if _, err = tmp.Write(b); err != nil {
 		return err
 	}
 
@@ -0,0 +1,112 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"vencordinstaller/buildinfo"
+)
+
+// Signatures live next to the signed file as <file>.sig, raw or base64 encoded
+
+var ErrSignatureMissing = errors.New("signature missing")
+var ErrSignatureInvalid = errors.New("signature invalid")
+
+type SigningKeys struct {
+	keys []ed25519.PublicKey
+	err  error // set if the pinned keys are malformed. Verification always fails in that case
+}
+
+var VencordSigningKeys = parseSigningKeys(buildinfo.VencordPublicKeys)
+var InstallerSigningKeys = parseSigningKeys(buildinfo.InstallerPublicKeys)
+
+func parseSigningKeys(s string) *SigningKeys {
+	sk := &SigningKeys{}
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		b, err := base64.StdEncoding.DecodeString(field)
+		if err == nil && len(b) != ed25519.PublicKeySize {
+			err = fmt.Errorf("expected %d bytes, got %d", ed25519.PublicKeySize, len(b))
+		}
+		if err != nil {
+			sk.err = fmt.Errorf("Malformed pinned public key %s: %w", field, err)
+			continue
+		}
+		sk.keys = append(sk.keys, b)
+	}
+	return sk
+}
+
+// Enabled reports whether keys were pinned at build time. Unofficial builds have none
+func (sk *SigningKeys) Enabled() bool {
+	return len(sk.keys) != 0 || sk.err != nil
+}
+
+// String describes the pinned keys for --version, so release builds can check they were actually pinned
+func (sk *SigningKeys) String() string {
+	switch {
+	case sk.err != nil:
+		return "malformed"
+	case len(sk.keys) == 0:
+		return "off"
+	default:
+		return fmt.Sprintf("%d key(s)", len(sk.keys))
+	}
+}
+
+// Verify checks that sig is a valid signature of data by one of the pinned keys
+func (sk *SigningKeys) Verify(name string, data, sig []byte) error {
+	if sk.err != nil {
+		return sk.err
+	}
+
+	sig, err := decodeSignature(sig)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %v", name, ErrSignatureInvalid, err)
+	}
+
+	for _, key := range sk.keys {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w. It was not signed by any trusted key and may have been tampered with", name, ErrSignatureInvalid)
+}
+
+func decodeSignature(b []byte) ([]byte, error) {
+	if len(b) == ed25519.SignatureSize {
+		return b, nil
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", ed25519.SignatureSize, len(sig))
+	}
+	return sig, nil
+}
+
+// fetchSignature downloads the signature of the release asset name
+func fetchSignature(ctx context.Context, release *GithubRelease, name string) ([]byte, error) {
+	i := SliceIndexFunc(release.Assets, func(ass GithubAsset) bool { return ass.Name == name+".sig" })
+	if i == -1 {
+		return nil, fmt.Errorf("%s: %w", name, ErrSignatureMissing)
+	}
+
+	sig, err := downloadBytesFromMirrors(ctx, assetUrls(release, release.Assets[i]))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download signature of %s: %w", name, err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * SPDX-License-Identifier: GPL-3.0
+ * Vencord Installer, a cross platform gui/cli app for installing Vencord
+ * Copyright (c) 2023 Vendicated and Vencord contributors
+ */
+
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newTestKey(t *testing.T) (string, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), priv
+}
+
+func TestParseSigningKeys(t *testing.T) {
+	key, _ := newTestKey(t)
+	other, _ := newTestKey(t)
+
+	tests := []struct {
+		name        string
+		keys        string
+		wantKeys    int
+		wantErr     bool
+		wantEnabled bool
+		wantString  string
+	}{
+		{"empty", "", 0, false, false, "off"},
+		{"whitespace", "  ", 0, false, false, "off"},
+		{"one key", key, 1, false, true, "1 key(s)"},
+		{"comma and space separated", key + ", " + other, 2, false, true, "2 key(s)"},
+		{"not base64", "not a key!", 0, true, true, "malformed"},
+		{"too short", base64.StdEncoding.EncodeToString([]byte("short")), 0, true, true, "malformed"},
+		{"one bad key", key + ",AAAA", 1, true, true, "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sk := parseSigningKeys(tt.keys)
+			if len(sk.keys) != tt.wantKeys || (sk.err != nil) != tt.wantErr {
+				t.Errorf("parseSigningKeys() = %d keys, err %v, want %d keys, error %v", len(sk.keys), sk.err, tt.wantKeys, tt.wantErr)
+			}
+			if sk.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", sk.Enabled(), tt.wantEnabled)
+			}
+			if sk.String() != tt.wantString {
+				t.Errorf("String() = %s, want %s", sk.String(), tt.wantString)
+			}
+		})
+	}
+}
+
+func TestSigningKeysVerify(t *testing.T) {
+	key, priv := newTestKey(t)
+	other, otherPriv := newTestKey(t)
+	data := []byte("console.log('Vencord')")
+	sig := ed25519.Sign(priv, data)
+
+	tests := []struct {
+		name    string
+		keys    string
+		data    []byte
+		sig     []byte
+		wantErr error
+	}{
+		{"raw signature", key, data, sig, nil},
+		{"base64 signature", key, data, []byte(base64.StdEncoding.EncodeToString(sig) + "\n"), nil},
+		{"one of several keys", other + "," + key, data, sig, nil},
+		{"wrong key", other, data, sig, ErrSignatureInvalid},
+		{"signed by another key", key, data, ed25519.Sign(otherPriv, data), ErrSignatureInvalid},
+		{"tampered payload", key, []byte("console.log('evil')"), sig, ErrSignatureInvalid},
+		{"truncated signature", key, data, []byte(base64.StdEncoding.EncodeToString(sig[:32])), ErrSignatureInvalid},
+		{"garbage signature", key, data, []byte("not a signature"), ErrSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseSigningKeys(tt.keys).Verify("patcher.js", tt.data, tt.sig)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Verify() = %v, want nil", err)
+			} else if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSigningKeysVerifyFailsWithMalformedKeys(t *testing.T) {
+	key, priv := newTestKey(t)
+	data := []byte("console.log('Vencord')")
+
+	// a typo in one of the pinned keys must not silently leave only the others in effect
+	if err := parseSigningKeys(key+",AAAA").Verify("patcher.js", data, ed25519.Sign(priv, data)); err == nil {
+		t.Error("Verify() succeeded with a malformed pinned key")
+	}
+}
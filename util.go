@@ -8,8 +8,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 )
@@ -22,6 +25,16 @@ func SliceMap[T, U any](arr []T, mapper func(T) U) []U {
 	return result
 }
 
+func SliceFilter[T any](arr []T, fn func(T) bool) []T {
+	var result []T
+	for _, e := range arr {
+		if fn(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
 func SliceIndexFunc[T any](slice []T, fn func(T) bool) int {
 	for i, e := range slice {
 		if fn(e) {
@@ -45,12 +58,45 @@ func SliceContains[T comparable](slice []T, item T) bool {
 	return SliceIndex(slice, item) != -1
 }
 
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func ExistsFile(path string) bool {
 	_, err := os.Stat(path)
 	Log.Debug("Checking if", path, "exists:", Ternary(err == nil, "Yes", "No"))
 	return err == nil
 }
 
+// FormatBytes formats n as a human readable size, e.g. 1.5 MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// SanitizeFileName replaces all characters that may not be valid in file names
+func SanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 func IsDirectory(path string) bool {
 	s, err := os.Stat(path)
 	if err != nil {